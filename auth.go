@@ -0,0 +1,173 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// ticketCookieName is the name of the cookie Proxmox expects a ticket in
+	ticketCookieName = "PVEAuthCookie"
+
+	// csrfHeaderName is the header Proxmox expects the CSRF prevention token in for write requests
+	csrfHeaderName = "CSRFPreventionToken"
+
+	// ticketRenewAfter is the age after which a ticket gets renewed. Proxmox tickets expire after two hours.
+	ticketRenewAfter = 90 * time.Minute
+)
+
+// ticketState holds the ticket of a client using ticket authentication
+type ticketState struct {
+	mu        sync.Mutex
+	ticket    string
+	csrfToken string
+	issued    time.Time
+
+	// renewing is the ticket request in flight, nil if there is none
+	renewing *ticketCall
+}
+
+// ticketCall is a ticket request that concurrent requests needing a ticket wait for
+type ticketCall struct {
+	done      chan struct{}
+	ticket    string
+	csrfToken string
+	err       error
+}
+
+// invalidate drops the current ticket so that the next request requests a new one
+func (t *ticketState) invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ticket = ""
+	t.csrfToken = ""
+}
+
+// accessTicketOptions contains the parameters for the /access/ticket endpoint
+type accessTicketOptions struct {
+	Username string `url:"username"`
+	Password string `url:"password"`
+}
+
+// accessTicketResponse contains the response for the /access/ticket endpoint
+type accessTicketResponse struct {
+	Data accessTicketData `json:"data"`
+}
+
+// accessTicketData contains the ticket data from an /access/ticket response
+type accessTicketData struct {
+	Ticket              string `json:"ticket"`
+	CSRFPreventionToken string `json:"CSRFPreventionToken"`
+	Username            string `json:"username"`
+}
+
+// usesTicketAuth reports whether the client authenticates with tickets rather than an API token
+func (c *Client) usesTicketAuth() bool {
+	return c.ticket != nil
+}
+
+// authenticate sets the authentication headers on a request according to
+// https://pve.proxmox.com/wiki/Proxmox_VE_API#Authentication
+func (c *Client) authenticate(req *http.Request) error {
+	if !c.usesTicketAuth() {
+		creds, err := c.tokenCredentials(req.Context())
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("PVEAPIToken=%s=%s", creds.TokenID, creds.Secret))
+		return nil
+	}
+
+	ticket, csrfToken, err := c.currentTicket(req.Context())
+	if err != nil {
+		return err
+	}
+
+	req.AddCookie(&http.Cookie{Name: ticketCookieName, Value: ticket})
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		req.Header.Set(csrfHeaderName, csrfToken)
+	}
+
+	return nil
+}
+
+// currentTicket returns a valid ticket and CSRF prevention token, requesting a new ticket if the current one is missing or due for renewal.
+// Concurrent requests share a single ticket request, and don't wait for it longer than their context allows.
+func (c *Client) currentTicket(ctx context.Context) (string, string, error) {
+	for {
+		c.ticket.mu.Lock()
+		if c.ticket.ticket != "" && time.Since(c.ticket.issued) < ticketRenewAfter {
+			ticket, csrfToken := c.ticket.ticket, c.ticket.csrfToken
+			c.ticket.mu.Unlock()
+			return ticket, csrfToken, nil
+		}
+
+		call := c.ticket.renewing
+		if call == nil {
+			call = &ticketCall{done: make(chan struct{})}
+			c.ticket.renewing = call
+			c.ticket.mu.Unlock()
+			c.renewTicket(ctx, call)
+			return call.ticket, call.csrfToken, call.err
+		}
+		c.ticket.mu.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return "", "", ctx.Err()
+		}
+
+		// Don't fail because the context of the request that requested the ticket was canceled
+		if !isContextError(call.err) {
+			return call.ticket, call.csrfToken, call.err
+		}
+	}
+}
+
+// renewTicket requests a ticket for a ticketCall without holding the lock, and stores it on success
+func (c *Client) renewTicket(ctx context.Context, call *ticketCall) {
+	defer close(call.done)
+
+	d, err := c.requestTicket(ctx)
+	if err == nil {
+		call.ticket, call.csrfToken = d.Ticket, d.CSRFPreventionToken
+	}
+	call.err = err
+
+	c.ticket.mu.Lock()
+	defer c.ticket.mu.Unlock()
+	c.ticket.renewing = nil
+	if err == nil {
+		c.ticket.ticket = call.ticket
+		c.ticket.csrfToken = call.csrfToken
+		c.ticket.issued = time.Now()
+	}
+}
+
+// requestTicket makes a POST request to the /access/ticket endpoint with the client's username and password
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/access/ticket
+func (c *Client) requestTicket(ctx context.Context) (*accessTicketData, error) {
+	opt := &accessTicketOptions{
+		Username: c.username,
+		Password: c.password,
+	}
+
+	req, err := c.NewRequestWithContext(ctx, http.MethodPost, "access/ticket", opt)
+	if err != nil {
+		return nil, err
+	}
+
+	d := new(accessTicketResponse)
+	if _, err := c.do(req, d); err != nil {
+		return nil, fmt.Errorf("error requesting Proxmox ticket: %w", err)
+	}
+	if d.Data.Ticket == "" {
+		return nil, fmt.Errorf("error requesting Proxmox ticket: empty ticket in response")
+	}
+
+	return &d.Data, nil
+}
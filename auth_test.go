@@ -0,0 +1,195 @@
+package proxmox
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupWithPassword(t *testing.T) (*http.ServeMux, *httptest.Server, *Client, *int32) {
+	mux := http.NewServeMux()
+	server := httptest.NewTLSServer(mux)
+
+	httpClient := http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+		},
+	}
+
+	client, err := NewClientWithPassword("root@pam", "secret",
+		WithBaseURL(fmt.Sprintf("%s/", server.URL)),
+		WithHTTPClient(&httpClient),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logins int32
+	mux.HandleFunc("/api2/json/access/ticket", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("username") != "root@pam" || r.FormValue("password") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt32(&logins, 1)
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprintf(w, `{"data":{"ticket":"PVE:root@pam:ticket%d","CSRFPreventionToken":"csrf%d","username":"root@pam"}}`, n, n)
+		if err != nil {
+			return
+		}
+	})
+
+	return mux, server, client, &logins
+}
+
+func TestNewClientWithPasswordRequiresCredentials(t *testing.T) {
+	_, err := NewClientWithPassword("", "secret")
+	require.Error(t, err)
+	_, err = NewClientWithPassword("root@pam", "")
+	require.Error(t, err)
+}
+
+func TestTicketAuth(t *testing.T) {
+	mux, server, client, logins := setupWithPassword(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(ticketCookieName)
+		if err != nil || cookie.Value != "PVE:root@pam:ticket1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(csrfHeaderName) != "" || r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, err = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+		if err != nil {
+			return
+		}
+	})
+	mux.HandleFunc("/api2/json/nodes/srv1/execute", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(csrfHeaderName) != "csrf1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, err := fmt.Fprint(w, `{"data":null}`)
+		if err != nil {
+			return
+		}
+	})
+
+	r, _, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Len(t, r.Data, 3)
+
+	req, err := client.NewRequest(http.MethodPost, "nodes/srv1/execute", nil)
+	require.NoError(t, err)
+	_, err = client.Do(req, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, int32(1), atomic.LoadInt32(logins))
+}
+
+func TestTicketAuthRenewal(t *testing.T) {
+	mux, server, client, logins := setupWithPassword(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+		if err != nil {
+			return
+		}
+	})
+
+	_, _, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(logins))
+
+	// Age the ticket past the renewal threshold
+	client.ticket.issued = time.Now().Add(-ticketRenewAfter)
+
+	_, _, err = client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(logins))
+}
+
+func TestTicketAuthRejectedTicket(t *testing.T) {
+	mux, server, client, logins := setupWithPassword(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		// Only accept the second ticket issued
+		cookie, err := r.Cookie(ticketCookieName)
+		if err != nil || cookie.Value != "PVE:root@pam:ticket2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, err = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+		if err != nil {
+			return
+		}
+	})
+
+	r, _, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Len(t, r.Data, 3)
+	require.Equal(t, int32(2), atomic.LoadInt32(logins))
+}
+
+func TestTicketAuthConcurrentRenewal(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewTLSServer(mux)
+	defer teardown(server)
+
+	client, err := NewClientWithPassword("root@pam", "secret", WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+	require.NoError(t, err)
+
+	// The ticket endpoint hangs until released
+	var logins int32
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	mux.HandleFunc("/api2/json/access/ticket", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&logins, 1) == 1 {
+			close(entered)
+		}
+		<-release
+		_, _ = fmt.Fprint(w, `{"data":{"ticket":"PVE:root@pam:ticket1","CSRFPreventionToken":"csrf1","username":"root@pam"}}`)
+	})
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+	})
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, errs[i] = client.Nodes.GetNodes()
+		}(i)
+	}
+	<-entered
+
+	// Requests don't wait for the ticket longer than their context allows
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, _, err = client.Nodes.GetNodesWithContext(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&logins))
+}
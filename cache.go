@@ -0,0 +1,306 @@
+package proxmox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheSweepInterval is how often expired responses are removed from the cache
+const cacheSweepInterval = time.Minute
+
+// CachePolicy configures caching of GET responses, for example for dashboards that read the same endpoints
+// several times per second. Responses are cached in memory by method, path and query. Concurrent identical requests
+// for a response that isn't cached share a single API request. Error responses are never cached.
+type CachePolicy struct {
+	// TTL is how long responses are cached for endpoints without a TTL in TTLs.
+	// Default: only endpoints in TTLs are cached
+	TTL time.Duration
+
+	// TTLs are how long responses of specific endpoints are cached, keyed by the API path with the node and guest ID
+	// replaced by placeholders, like "cluster/resources", "nodes" or "nodes/{node}/qemu/{vmid}/status/current".
+	// A TTL of 0 disables caching an endpoint.
+	TTLs map[string]time.Duration
+}
+
+// WithCache enables caching GET responses according to the policy.
+// A POST, PUT or DELETE request made with the client removes the cached responses of its path, its parent path and the
+// paths below them, e.g. starting a VM with nodes/srv1/qemu/100/status/start removes nodes/srv1/qemu/100/status/current.
+// Other responses the change shows up in, like the nodes/srv1/qemu or cluster/resources lists, and changes made elsewhere,
+// e.g. in the web UI, are only seen once the cached responses expired or were removed with InvalidateCache.
+// Default: responses are not cached
+func WithCache(policy CachePolicy) ClientOptionFunc {
+	return func(c *Client) error {
+		if policy.TTL < 0 {
+			return errors.New("cache TTL must not be negative")
+		}
+
+		ttls := make(map[string]time.Duration, len(policy.TTLs))
+		for path, ttl := range policy.TTLs {
+			if ttl < 0 {
+				return errors.New("cache TTL of " + path + " must not be negative")
+			}
+			ttls[strings.Trim(path, "/")] = ttl
+		}
+
+		c.cache = &responseCache{
+			ttl:       policy.TTL,
+			ttls:      ttls,
+			entries:   map[string]*cacheEntry{},
+			calls:     map[string]*cacheCall{},
+			lastSweep: time.Now(),
+		}
+		return nil
+	}
+}
+
+// InvalidateCache removes the cached responses of any of the given API paths and the paths below them, like "nodes/srv1".
+// Without paths, every cached response is removed. It does nothing if the client doesn't cache responses.
+func (c *Client) InvalidateCache(paths ...string) {
+	if c.cache == nil {
+		return
+	}
+	c.cache.invalidate(paths...)
+}
+
+// doCached sends an API request, serving GET requests from the cache if enabled
+func (c *Client) doCached(req *http.Request, v interface{}) (*http.Response, error) {
+	if c.cache == nil {
+		return c.doLimited(req, v)
+	}
+
+	path := strings.Trim(strings.TrimPrefix(req.URL.Path, c.baseURL.Path), "/")
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		// The request may have taken effect even if it failed
+		defer c.cache.invalidate(changedPaths(path)...)
+		return c.doLimited(req, v)
+	}
+
+	template, _, _ := pathTemplate(path)
+	ttl := c.cache.ttlFor(template)
+	if req.Method != http.MethodGet || ttl <= 0 {
+		return c.doLimited(req, v)
+	}
+
+	key := req.Method + " " + path + "?" + req.URL.RawQuery
+	entry, err := c.cache.get(req.Context(), key, ttl, func() (*cacheEntry, error) {
+		var body bytes.Buffer
+		resp, err := c.doLimited(req, &body)
+		if err != nil {
+			return &cacheEntry{resp: resp}, err
+		}
+		return &cacheEntry{resp: resp, body: body.Bytes()}, nil
+	})
+	if err != nil {
+		if entry == nil || entry.resp == nil {
+			return nil, err
+		}
+		return entry.response(req), err
+	}
+
+	resp := entry.response(req)
+	if v != nil {
+		if w, ok := v.(io.Writer); ok {
+			_, err = w.Write(entry.body)
+		} else {
+			err = json.Unmarshal(entry.body, v)
+		}
+	}
+
+	return resp, err
+}
+
+// responseCache caches the responses of GET requests
+type responseCache struct {
+	// ttl is the TTL of endpoints without a TTL in ttls
+	ttl time.Duration
+
+	// ttls are the TTLs of specific endpoints, keyed by path template
+	ttls map[string]time.Duration
+
+	mu sync.Mutex
+
+	// entries are the cached responses, keyed by method, path and query
+	entries map[string]*cacheEntry
+
+	// calls are the requests in flight, keyed like entries
+	calls map[string]*cacheCall
+
+	// generation is incremented when the cache is invalidated, so responses of requests in flight aren't cached
+	generation uint64
+
+	// lastSweep is when expired responses were last removed
+	lastSweep time.Time
+}
+
+// cacheEntry is a cached response
+type cacheEntry struct {
+	resp    *http.Response
+	body    []byte
+	expires time.Time
+}
+
+// response returns a copy of the cached response for a request. Its body was already read.
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	resp := *e.resp
+	resp.Header = e.resp.Header.Clone()
+	resp.Body = http.NoBody
+	resp.Request = req
+	return &resp
+}
+
+// cacheCall is a request in flight whose response is shared by identical requests
+type cacheCall struct {
+	done  chan struct{}
+	entry *cacheEntry
+	err   error
+
+	// generation is the generation of the cache when the request was sent
+	generation uint64
+}
+
+// ttlFor returns the TTL of an endpoint
+func (rc *responseCache) ttlFor(template string) time.Duration {
+	if ttl, ok := rc.ttls[template]; ok {
+		return ttl
+	}
+	return rc.ttl
+}
+
+// get returns the cached response for key, or calls fetch to get it. Concurrent calls for the same key share a single fetch,
+// unless the cache is invalidated while they wait for it. If fetch fails, its entry is returned along with the error but not cached.
+// Entries are shared, so callers must return copies of their responses. Waiters get their own copy of an APIError.
+func (rc *responseCache) get(ctx context.Context, key string, ttl time.Duration, fetch func() (*cacheEntry, error)) (*cacheEntry, error) {
+	for {
+		rc.mu.Lock()
+		if e, ok := rc.entries[key]; ok {
+			if time.Now().Before(e.expires) {
+				rc.mu.Unlock()
+				return e, nil
+			}
+			delete(rc.entries, key)
+		}
+
+		if call, ok := rc.calls[key]; ok {
+			rc.mu.Unlock()
+			select {
+			case <-call.done:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+			// Don't fail because the context of the request that fetched the response was canceled,
+			// and don't use a response that may predate an invalidation that happened while waiting for it
+			rc.mu.Lock()
+			invalidated := rc.generation != call.generation
+			rc.mu.Unlock()
+			if invalidated || isContextError(call.err) {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return call.entry, copyAPIError(call.err)
+		}
+
+		call := &cacheCall{done: make(chan struct{}), generation: rc.generation}
+		rc.calls[key] = call
+		rc.mu.Unlock()
+
+		call.entry, call.err = fetch()
+
+		rc.mu.Lock()
+		if rc.calls[key] == call {
+			delete(rc.calls, key)
+		}
+		if call.err == nil && rc.generation == call.generation {
+			call.entry.expires = time.Now().Add(ttl)
+			rc.entries[key] = call.entry
+			rc.sweepLocked()
+		}
+		rc.mu.Unlock()
+		close(call.done)
+
+		return call.entry, call.err
+	}
+}
+
+// invalidate removes the cached responses of the given paths and the paths below them, or all cached responses
+func (rc *responseCache) invalidate(paths ...string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	// Requests in flight may have been sent before the change, so their responses are neither cached nor shared.
+	// Requests waiting for them see the new generation and send their own.
+	rc.generation++
+	rc.calls = map[string]*cacheCall{}
+
+	if len(paths) == 0 {
+		rc.entries = map[string]*cacheEntry{}
+		return
+	}
+
+	for key := range rc.entries {
+		_, path, _ := strings.Cut(key, " ")
+		path, _, _ = strings.Cut(path, "?")
+		for _, p := range paths {
+			if p = strings.Trim(p, "/"); p == "" || path == p || strings.HasPrefix(path, p+"/") {
+				delete(rc.entries, key)
+				break
+			}
+		}
+	}
+}
+
+// changedPaths returns the paths whose cached responses a request changing path may have made stale: the path and its parent
+func changedPaths(path string) []string {
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return []string{path}
+	}
+	return []string{path, path[:i]}
+}
+
+// sweepLocked removes expired responses if they weren't removed recently. The caller must hold rc.mu.
+func (rc *responseCache) sweepLocked() {
+	now := time.Now()
+	if now.Sub(rc.lastSweep) < cacheSweepInterval {
+		return
+	}
+	rc.lastSweep = now
+
+	for key, e := range rc.entries {
+		if !now.Before(e.expires) {
+			delete(rc.entries, key)
+		}
+	}
+}
+
+// copyAPIError returns a copy of err if it's an APIError, so callers sharing a failed request can't change each other's error
+func copyAPIError(err error) error {
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		return err
+	}
+
+	c := *apiErr
+	c.Body = bytes.Clone(apiErr.Body)
+	if apiErr.Errors != nil {
+		c.Errors = make(map[string]string, len(apiErr.Errors))
+		for k, v := range apiErr.Errors {
+			c.Errors[k] = v
+		}
+	}
+	return &c
+}
+
+// isContextError reports whether an error is caused by a canceled context or an exceeded deadline
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
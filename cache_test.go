@@ -0,0 +1,317 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingHandler serves a fixture and counts the requests it served
+func countingHandler(path string, count *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(count, 1)
+		_, _ = fmt.Fprint(w, fixture(path))
+	}
+}
+
+func TestWithCache(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithCache(CachePolicy{TTLs: map[string]time.Duration{"nodes": time.Minute}})(client))
+
+	var nodes, status int32
+	mux.HandleFunc("/api2/json/nodes", countingHandler("nodes/get_nodes.json", &nodes))
+	mux.HandleFunc("/api2/json/nodes/node1/status", countingHandler("nodes/get_node_status.json", &status))
+
+	first, _, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+	second, resp, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int32(1), atomic.LoadInt32(&nodes))
+
+	// Endpoints without a TTL aren't cached
+	for i := 0; i < 2; i++ {
+		_, _, err = client.Nodes.GetNodeStatus("node1")
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&status))
+}
+
+func TestWithCacheTemplateAndQuery(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithCache(CachePolicy{TTLs: map[string]time.Duration{"/nodes/{node}/qemu/": time.Minute}})(client))
+
+	var count int32
+	mux.HandleFunc("/api2/json/nodes/node1/qemu", countingHandler("nodes/get_node_qemu.json", &count))
+	mux.HandleFunc("/api2/json/nodes/node2/qemu", countingHandler("nodes/get_node_qemu.json", &count))
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		_, _, err := client.Nodes.GetNodeQemu("node1")
+		require.NoError(t, err)
+		_, _, err = client.Nodes.GetNodeQemu("node2")
+		require.NoError(t, err)
+		_, _, err = Get[[]GetNodeQemuData](ctx, client, "nodes/node1/qemu", map[string]string{"full": "1"})
+		require.NoError(t, err)
+	}
+
+	// Each node and query is cached separately
+	require.Equal(t, int32(3), atomic.LoadInt32(&count))
+}
+
+func TestWithCacheExpires(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithCache(CachePolicy{TTL: 20 * time.Millisecond})(client))
+
+	var count int32
+	mux.HandleFunc("/api2/json/nodes", countingHandler("nodes/get_nodes.json", &count))
+
+	_, _, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+	_, _, err = client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&count))
+}
+
+func TestWithCacheSingleFlight(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithCache(CachePolicy{TTL: time.Minute})(client))
+
+	var count int32
+	release := make(chan struct{})
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		<-release
+		_, _ = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+	})
+
+	var wg sync.WaitGroup
+	results := make([]*GetNodesResponse, 5)
+	errs := make([]error, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _, errs[i] = client.Nodes.GetNodes()
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, int32(1), atomic.LoadInt32(&count))
+	for i := range results {
+		require.NoError(t, errs[i])
+		require.Len(t, results[i].Data, 3)
+	}
+}
+
+func TestWithCacheSingleFlightCanceled(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithCache(CachePolicy{TTL: time.Minute})(client))
+
+	var count int32
+	arrived := make(chan struct{}, 2)
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			arrived <- struct{}{}
+			<-r.Context().Done()
+			return
+		}
+		_, _ = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, _, err := client.Nodes.GetNodesWithContext(ctx)
+		done <- err
+	}()
+	<-arrived
+
+	// A request waiting for the canceled request sends its own
+	waiting := make(chan error)
+	go func() {
+		_, _, err := client.Nodes.GetNodes()
+		waiting <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	require.ErrorIs(t, <-done, context.Canceled)
+	require.NoError(t, <-waiting)
+	require.Equal(t, int32(2), atomic.LoadInt32(&count))
+}
+
+func TestWithCacheSingleFlightInvalidated(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithCache(CachePolicy{TTL: time.Minute})(client))
+
+	var count int32
+	arrived := make(chan struct{}, 1)
+	release := make(chan struct{})
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			arrived <- struct{}{}
+			<-release
+		}
+		_, _ = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+	})
+
+	done := make(chan error)
+	go func() {
+		_, _, err := client.Nodes.GetNodes()
+		done <- err
+	}()
+	<-arrived
+
+	// A request waiting for a response that may predate an invalidation sends its own
+	waiting := make(chan error)
+	go func() {
+		_, _, err := client.Nodes.GetNodes()
+		waiting <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	client.InvalidateCache()
+	close(release)
+
+	require.NoError(t, <-done)
+	require.NoError(t, <-waiting)
+	require.Equal(t, int32(2), atomic.LoadInt32(&count))
+}
+
+func TestWithCacheSingleFlightError(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithCache(CachePolicy{TTL: time.Minute})(client))
+
+	var count int32
+	release := make(chan struct{})
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		<-release
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	var wg sync.WaitGroup
+	resps := make([]*http.Response, 2)
+	errs := make([]error, 2)
+	for i := range resps {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, resps[i], errs[i] = client.Nodes.GetNodes()
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// Every request gets its own copy of the shared error response and error
+	require.Equal(t, int32(1), atomic.LoadInt32(&count))
+	require.Equal(t, http.StatusInternalServerError, resps[0].StatusCode)
+	require.Equal(t, http.StatusInternalServerError, resps[1].StatusCode)
+	require.True(t, resps[0] != resps[1])
+
+	var first, second *APIError
+	require.ErrorAs(t, errs[0], &first)
+	require.ErrorAs(t, errs[1], &second)
+	require.Equal(t, first, second)
+	require.True(t, first != second)
+}
+
+func TestWithCacheErrorsNotCached(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithCache(CachePolicy{TTL: time.Minute})(client))
+
+	var count int32
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	for i := 0; i < 2; i++ {
+		_, resp, err := client.Nodes.GetNodes()
+		require.Error(t, err)
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&count))
+}
+
+func TestWithCacheInvalidation(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithCache(CachePolicy{TTL: time.Minute})(client))
+
+	var nodes, status, current int32
+	mux.HandleFunc("/api2/json/nodes", countingHandler("nodes/get_nodes.json", &nodes))
+	mux.HandleFunc("/api2/json/nodes/node1/status", countingHandler("nodes/get_node_status.json", &status))
+	mux.HandleFunc("/api2/json/nodes/node1/qemu/100/status/current", countingHandler("nodes/get_qemu_status_current.json", &current))
+	mux.HandleFunc("/api2/json/nodes/node1/qemu/100/status/start", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"data":"UPID:node1:00000001:00000001:00000001:qmstart:100:root@pam:"}`)
+	})
+
+	get := func() {
+		_, _, err := client.Nodes.GetNodes()
+		require.NoError(t, err)
+		_, _, err = client.Nodes.GetNodeStatus("node1")
+		require.NoError(t, err)
+		req, err := client.NewRequest(http.MethodGet, "nodes/node1/qemu/100/status/current", nil)
+		require.NoError(t, err)
+		_, err = client.Do(req, nil)
+		require.NoError(t, err)
+	}
+
+	get()
+	get()
+	require.Equal(t, int32(1), atomic.LoadInt32(&nodes))
+	require.Equal(t, int32(1), atomic.LoadInt32(&status))
+	require.Equal(t, int32(1), atomic.LoadInt32(&current))
+
+	// Explicit invalidation removes the path and the paths below it
+	client.InvalidateCache("nodes/node1")
+	get()
+	require.Equal(t, int32(1), atomic.LoadInt32(&nodes))
+	require.Equal(t, int32(2), atomic.LoadInt32(&status))
+	require.Equal(t, int32(2), atomic.LoadInt32(&current))
+
+	// Mutating requests remove the responses of their path, their parent path and the paths below them
+	req, err := client.NewRequest(http.MethodPost, "nodes/node1/qemu/100/status/start", nil)
+	require.NoError(t, err)
+	_, err = client.Do(req, nil)
+	require.NoError(t, err)
+	get()
+	require.Equal(t, int32(1), atomic.LoadInt32(&nodes))
+	require.Equal(t, int32(2), atomic.LoadInt32(&status))
+	require.Equal(t, int32(3), atomic.LoadInt32(&current))
+}
+
+func TestChangedPaths(t *testing.T) {
+	require.Equal(t, []string{"nodes/node1/qemu/100/status/start", "nodes/node1/qemu/100/status"}, changedPaths("nodes/node1/qemu/100/status/start"))
+	require.Equal(t, []string{"nodes/node1/qemu/100/config", "nodes/node1/qemu/100"}, changedPaths("nodes/node1/qemu/100/config"))
+	require.Equal(t, []string{"pools"}, changedPaths("pools"))
+}
+
+func TestWithCacheInvalid(t *testing.T) {
+	_, err := NewClient("id", "token", WithCache(CachePolicy{TTL: -time.Second}))
+	require.Error(t, err)
+
+	_, err = NewClient("id", "token", WithCache(CachePolicy{TTLs: map[string]time.Duration{"nodes": -time.Second}}))
+	require.Error(t, err)
+}
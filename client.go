@@ -1,10 +1,13 @@
 package proxmox
 
 import (
+	"crypto/x509"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 const (
@@ -21,15 +24,64 @@ type Client struct {
 	// but can be changed to any remote endpoint.
 	baseURL *url.URL
 
-	// tokenID is the identifier given for a Proxmox API token
-	tokenID string
+	// credentials provides the API token, nil when using ticket authentication
+	credentials CredentialProvider
 
-	// token is the token secret
-	token string
+	// username is the user, including realm, used for ticket authentication
+	username string
+
+	// password is the password used for ticket authentication
+	password string
+
+	// ticket is the current ticket when using ticket authentication
+	ticket *ticketState
+
+	// endpoints holds the base URLs to fail over between, or only baseURL.
+	// It's replaced by options only, so it can be read without locking once the client was created.
+	endpoints *endpointPool
+
+	// endpointCooldown and endpointCheckInterval are the endpoint options, applied to endpoints once all options were applied
+	endpointCooldown      *time.Duration
+	endpointCheckInterval time.Duration
+
+	// middleware wraps the round trip of every request
+	middleware []Middleware
+
+	// transport sends requests through the middleware and the HTTP client, see buildTransport
+	transport http.RoundTripper
+
+	// instrumenter is notified around every API call if set
+	instrumenter Instrumenter
+
+	// pins are the TLS certificate fingerprints the client trusts, certificates are verified as usual if nil
+	pins *pinSet
+
+	// rootCAs are the certificate authorities the client trusts, the system roots are used if nil
+	rootCAs *x509.CertPool
+
+	// insecure disables TLS certificate verification
+	insecure bool
+
+	// serverVersion caches the Proxmox VE version of the server
+	serverVersion *serverVersion
+
+	// retry is the policy for retrying failed requests, requests are attempted once if nil
+	retry *RetryPolicy
+
+	// rateLimits limit how fast and how many requests are sent, requests are not limited if nil
+	rateLimits *rateLimits
+
+	// cache holds cached GET responses, responses are not cached if nil
+	cache *responseCache
+
+	// logger receives debug logs of every request, nothing is logged if nil
+	logger *slog.Logger
 
 	// Services for each resource in the Proxmox API
 	Nodes   *NodeService
 	Cluster *ClusterService
+	Tasks   *TaskService
+	Version *VersionService
 }
 
 // NewClient returns a new Proxmox API client
@@ -39,13 +91,35 @@ func NewClient(tokenID string, token string, options ...ClientOptionFunc) (*Clie
 	}
 
 	c := &Client{
-		tokenID: tokenID,
-		token:   token,
+		credentials: StaticCredentials(tokenID, token),
+	}
+
+	return newClient(c, options...)
+}
+
+// NewClientWithPassword returns a new Proxmox API client that authenticates with a username and password.
+// The username must include its realm, for example "root@pam" or "automation@pve".
+// The client requests a ticket from the /access/ticket endpoint on first use and renews it before it expires.
+func NewClientWithPassword(username string, password string, options ...ClientOptionFunc) (*Client, error) {
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("can not create Proxmox API client without a username and password")
 	}
 
+	c := &Client{
+		username: username,
+		password: password,
+		ticket:   &ticketState{},
+	}
+
+	return newClient(c, options...)
+}
+
+// newClient sets the client defaults, applies the given options and creates the API services
+func newClient(c *Client, options ...ClientOptionFunc) (*Client, error) {
 	// Set the client default fields
 	_ = c.setBaseURL(defaultBaseURL)
 	_ = c.setHTTPClient(&http.Client{})
+	c.serverVersion = &serverVersion{}
 
 	// Apply any given options
 	for _, fn := range options {
@@ -57,9 +131,22 @@ func NewClient(tokenID string, token string, options ...ClientOptionFunc) (*Clie
 		}
 	}
 
+	// Apply the endpoint options once the base URLs are final
+	if err := c.configureEndpoints(); err != nil {
+		return nil, err
+	}
+
+	// Apply the TLS options once the HTTP client is final
+	if err := c.configureTLS(); err != nil {
+		return nil, err
+	}
+	c.buildTransport()
+
 	// Create all the Proxmox API services
 	c.Nodes = &NodeService{client: c}
 	c.Cluster = &ClusterService{client: c}
+	c.Tasks = &TaskService{client: c}
+	c.Version = &VersionService{client: c}
 
 	return c, nil
 }
@@ -78,6 +165,20 @@ func WithBaseURL(urlStr string) ClientOptionFunc {
 
 // setBaseURL sets the URL for API requests
 func (c *Client) setBaseURL(urlStr string) error {
+	baseURL, err := c.parseBaseURL(urlStr)
+	if err != nil {
+		return err
+	}
+
+	// Update the base URL of the client, replacing any failover endpoints
+	c.baseURL = baseURL
+	c.endpoints = newEndpointPool(baseURL)
+
+	return nil
+}
+
+// parseBaseURL parses a URL for API requests, applying the API path if unspecified
+func (c *Client) parseBaseURL(urlStr string) (*url.URL, error) {
 	// Make sure the given URL end with a slash
 	if !strings.HasSuffix(urlStr, "/") {
 		urlStr += "/"
@@ -85,17 +186,14 @@ func (c *Client) setBaseURL(urlStr string) error {
 
 	baseURL, err := url.Parse(urlStr)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if !strings.HasSuffix(baseURL.Path, apiPath) {
 		baseURL.Path += apiPath
 	}
 
-	// Update the base URL of the client
-	c.baseURL = baseURL
-
-	return nil
+	return baseURL, nil
 }
 
 // WithHTTPClient sets the HTTP client for API requests to something other than the default Go http Client
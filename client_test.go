@@ -9,7 +9,7 @@ import (
 	"testing"
 )
 
-func setup(t *testing.T) (*http.ServeMux, *httptest.Server, *Client) {
+func setup(t *testing.T, options ...ClientOptionFunc) (*http.ServeMux, *httptest.Server, *Client) {
 	mux := http.NewServeMux()
 	server := httptest.NewTLSServer(mux)
 
@@ -21,10 +21,11 @@ func setup(t *testing.T) (*http.ServeMux, *httptest.Server, *Client) {
 		},
 	}
 
-	client, err := NewClient("test-token-id", "test-token",
+	options = append([]ClientOptionFunc{
 		WithBaseURL(fmt.Sprintf("%s/", server.URL)),
 		WithHTTPClient(&httpClient),
-	)
+	}, options...)
+	client, err := NewClient("test-token-id", "test-token", options...)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1,6 +1,9 @@
 package proxmox
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 // ClusterService is the service that encapsulates node API methods
 type ClusterService struct {
@@ -29,8 +32,13 @@ type GetClusterStatusData struct {
 // GetClusterStatus makes a GET request to the /cluster/status endpoint
 // https://pve.proxmox.com/pve-docs/api-viewer/index.html#/cluster/status
 func (s *ClusterService) GetClusterStatus() (*GetClusterStatusResponse, *http.Response, error) {
+	return s.GetClusterStatusWithContext(context.Background())
+}
+
+// GetClusterStatusWithContext is like GetClusterStatus but uses the given context for the request
+func (s *ClusterService) GetClusterStatusWithContext(ctx context.Context) (*GetClusterStatusResponse, *http.Response, error) {
 	u := "cluster/status"
-	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "ClusterService.GetClusterStatus"), http.MethodGet, u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -84,8 +92,13 @@ type GetClusterResourcesData struct {
 // GetClusterResources makes a GET request to the /cluster/resources endpoint
 // https://pve.proxmox.com/pve-docs/api-viewer/index.html#/cluster/resources
 func (s *ClusterService) GetClusterResources() (*GetClusterResourcesResponse, *http.Response, error) {
+	return s.GetClusterResourcesWithContext(context.Background())
+}
+
+// GetClusterResourcesWithContext is like GetClusterResources but uses the given context for the request
+func (s *ClusterService) GetClusterResourcesWithContext(ctx context.Context) (*GetClusterResourcesResponse, *http.Response, error) {
 	u := "cluster/resources"
-	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "ClusterService.GetClusterResources"), http.MethodGet, u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -109,11 +122,16 @@ type GetClusterCephStatusData struct {
 	Health CephHealthStatus `json:"health"`
 }
 
-// GetClusterCephStatus makes a GET request to the /cluster/resources endpoint
-// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/cluster/resources
+// GetClusterCephStatus makes a GET request to the /cluster/ceph/status endpoint
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/cluster/ceph/status
 func (s *ClusterService) GetClusterCephStatus() (*GetClusterCephStatusResponse, *http.Response, error) {
+	return s.GetClusterCephStatusWithContext(context.Background())
+}
+
+// GetClusterCephStatusWithContext is like GetClusterCephStatus but uses the given context for the request
+func (s *ClusterService) GetClusterCephStatusWithContext(ctx context.Context) (*GetClusterCephStatusResponse, *http.Response, error) {
 	u := "cluster/ceph/status"
-	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "ClusterService.GetClusterCephStatus"), http.MethodGet, u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
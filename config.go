@@ -0,0 +1,243 @@
+package proxmox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables read by NewClientFromEnv
+const (
+	EnvURL         = "PVE_URL"
+	EnvTokenID     = "PVE_TOKEN_ID"
+	EnvTokenSecret = "PVE_TOKEN_SECRET"
+	EnvTokenFile   = "PVE_TOKEN_FILE"
+	EnvUsername    = "PVE_USER"
+	EnvPassword    = "PVE_PASSWORD"
+	EnvCAFile      = "PVE_CA_FILE"
+	EnvFingerprint = "PVE_FINGERPRINT"
+	EnvInsecure    = "PVE_INSECURE"
+)
+
+// Profile holds the settings to connect to one Proxmox VE cluster
+type Profile struct {
+	// URL is the base URL for API requests, e.g. https://10.0.0.10:8006/
+	URL string `yaml:"url" json:"url"`
+
+	// URLs are several base URLs to fail over between, used instead of URL
+	URLs []string `yaml:"urls" json:"urls"`
+
+	// TokenID and TokenSecret authenticate with an API token
+	TokenID     string `yaml:"token_id" json:"token_id"`
+	TokenSecret string `yaml:"token_secret" json:"token_secret"`
+
+	// TokenFile is a file containing an API token in the format tokenID=secret, used instead of TokenID and TokenSecret.
+	// The file is reread when it changes, see FileCredentials.
+	TokenFile string `yaml:"token_file" json:"token_file"`
+
+	// Username and Password authenticate with a ticket, used when no token is set
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+
+	// CAFile is a PEM file of certificate authorities to trust
+	CAFile string `yaml:"ca_file" json:"ca_file"`
+
+	// Fingerprints are SHA-256 TLS certificate fingerprints to pin
+	Fingerprints []string `yaml:"fingerprints" json:"fingerprints"`
+
+	// Insecure disables TLS certificate verification
+	Insecure bool `yaml:"insecure" json:"insecure"`
+}
+
+// Config is a profile file with several named clusters, for example:
+//
+//	default: prod
+//	clusters:
+//	  prod:
+//	    urls: [https://10.0.0.10:8006, https://10.0.0.11:8006]
+//	    token_id: automation@pve!ci
+//	    token_secret: 00000000-0000-0000-0000-000000000000
+//	    ca_file: /etc/ssl/pve-root-ca.pem
+//	  lab:
+//	    url: https://192.168.1.5:8006
+//	    username: root@pam
+//	    password: secret
+//	    fingerprints: ["AB:CD:..."]
+//
+// The same structure can be written as JSON.
+type Config struct {
+	// Default is the name of the cluster used when no name is given
+	Default string `yaml:"default" json:"default"`
+
+	// Clusters are the profiles of the clusters by name
+	Clusters map[string]Profile `yaml:"clusters" json:"clusters"`
+}
+
+// NewClientFromEnv returns a new Proxmox API client configured from environment variables:
+//
+//	PVE_URL           base URL, or a comma separated list of base URLs to fail over between
+//	PVE_TOKEN_ID      API token ID, e.g. root@pam!automation
+//	PVE_TOKEN_SECRET  API token secret
+//	PVE_TOKEN_FILE    file containing an API token as tokenID=secret, reread when it changes
+//	PVE_USER          username including realm, used with PVE_PASSWORD when no token is set
+//	PVE_PASSWORD      password
+//	PVE_CA_FILE       PEM file of certificate authorities to trust
+//	PVE_FINGERPRINT   comma separated SHA-256 TLS certificate fingerprints to pin
+//	PVE_INSECURE      disables TLS certificate verification if true
+//
+// The given options are applied after the ones from the environment.
+func NewClientFromEnv(options ...ClientOptionFunc) (*Client, error) {
+	p := Profile{
+		TokenID:     os.Getenv(EnvTokenID),
+		TokenSecret: os.Getenv(EnvTokenSecret),
+		TokenFile:   os.Getenv(EnvTokenFile),
+		Username:    os.Getenv(EnvUsername),
+		Password:    os.Getenv(EnvPassword),
+		CAFile:      os.Getenv(EnvCAFile),
+	}
+	if v := os.Getenv(EnvURL); v != "" {
+		p.URLs = splitList(v)
+	}
+	if v := os.Getenv(EnvFingerprint); v != "" {
+		p.Fingerprints = splitList(v)
+	}
+	if v := os.Getenv(EnvInsecure); v != "" {
+		insecure, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: %w", EnvInsecure, v, err)
+		}
+		p.Insecure = insecure
+	}
+
+	return p.NewClient(options...)
+}
+
+// LoadConfig reads a YAML or JSON profile file, see Config for its structure
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Proxmox config: %w", err)
+	}
+
+	// YAML is a superset of JSON, so both are parsed the same way. Unknown keys are rejected to catch typos.
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error parsing Proxmox config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// NewClientFromConfig reads the profile file at path and returns a new Proxmox API client for the named cluster.
+// See Config.Profile for how the cluster is chosen when name is empty.
+func NewClientFromConfig(path string, name string, options ...ClientOptionFunc) (*Client, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := cfg.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.NewClient(options...)
+}
+
+// Profile returns the profile of the named cluster. If name is empty the default cluster is returned,
+// or the only cluster if the config has one and no default.
+func (cfg *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = cfg.Default
+	}
+	if name == "" && len(cfg.Clusters) == 1 {
+		for n := range cfg.Clusters {
+			name = n
+		}
+	}
+	if name == "" {
+		return nil, errors.New("no cluster name given and the Proxmox config has no default cluster")
+	}
+
+	p, ok := cfg.Clusters[name]
+	if !ok {
+		return nil, fmt.Errorf("cluster %q not found in the Proxmox config, available clusters: %s", name, strings.Join(cfg.Names(), ", "))
+	}
+
+	return &p, nil
+}
+
+// Names returns the names of the clusters in the config in sorted order
+func (cfg *Config) Names() []string {
+	names := make([]string, 0, len(cfg.Clusters))
+	for name := range cfg.Clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewClient returns a new Proxmox API client for the profile. The given options are applied after the ones from the profile.
+func (p *Profile) NewClient(options ...ClientOptionFunc) (*Client, error) {
+	opts := p.options()
+	opts = append(opts, options...)
+
+	if p.TokenFile != "" {
+		creds, err := NewFileCredentials(p.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		return NewClientWithCredentials(creds, opts...)
+	}
+	if p.TokenID != "" || p.TokenSecret != "" {
+		return NewClient(p.TokenID, p.TokenSecret, opts...)
+	}
+	if p.Username != "" || p.Password != "" {
+		return NewClientWithPassword(p.Username, p.Password, opts...)
+	}
+
+	return nil, errors.New("can not create Proxmox API client without a token ID and token, a token file, or a username and password")
+}
+
+// options returns the client options for the settings of the profile
+func (p *Profile) options() []ClientOptionFunc {
+	var opts []ClientOptionFunc
+
+	switch {
+	case len(p.URLs) > 0:
+		opts = append(opts, WithBaseURLs(p.URLs...))
+	case p.URL != "":
+		opts = append(opts, WithBaseURL(p.URL))
+	}
+	if p.CAFile != "" {
+		opts = append(opts, WithCAFile(p.CAFile))
+	}
+	if len(p.Fingerprints) > 0 {
+		opts = append(opts, WithTLSFingerprint(p.Fingerprints...))
+	}
+	if p.Insecure {
+		opts = append(opts, WithInsecureSkipVerify())
+	}
+
+	return opts
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
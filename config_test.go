@@ -0,0 +1,101 @@
+package proxmox
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientFromEnv(t *testing.T) {
+	t.Setenv(EnvURL, "https://10.0.0.10:8006, https://10.0.0.11:8006")
+	t.Setenv(EnvTokenID, "root@pam!automation")
+	t.Setenv(EnvTokenSecret, "test-token")
+	t.Setenv(EnvFingerprint, "AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB")
+
+	c, err := NewClientFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, StaticCredentials("root@pam!automation", "test-token"), c.credentials)
+	require.Equal(t, "https://10.0.0.10:8006/api2/json/", c.baseURL.String())
+	require.Equal(t, 2, c.endpoints.size())
+	require.Len(t, c.TLSFingerprints(), 1)
+
+	// Options override the environment
+	c, err = NewClientFromEnv(WithBaseURL("https://10.0.0.12:8006"))
+	require.NoError(t, err)
+	require.Equal(t, "https://10.0.0.12:8006/api2/json/", c.ActiveEndpoint())
+
+	// Falls back to a username and password
+	t.Setenv(EnvTokenID, "")
+	t.Setenv(EnvTokenSecret, "")
+	t.Setenv(EnvUsername, "root@pam")
+	t.Setenv(EnvPassword, "secret")
+	t.Setenv(EnvFingerprint, "")
+	t.Setenv(EnvInsecure, "true")
+	c, err = NewClientFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, "root@pam", c.username)
+	require.True(t, c.client.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
+
+	t.Setenv(EnvInsecure, "maybe")
+	_, err = NewClientFromEnv()
+	require.Error(t, err)
+
+	t.Setenv(EnvInsecure, "")
+	t.Setenv(EnvCAFile, "testdata/config/missing.pem")
+	_, err = NewClientFromEnv()
+	require.Error(t, err)
+
+	t.Setenv(EnvCAFile, "")
+	t.Setenv(EnvUsername, "")
+	t.Setenv(EnvPassword, "")
+	_, err = NewClientFromEnv()
+	require.Error(t, err)
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig("testdata/config/clusters.yaml")
+	require.NoError(t, err)
+	require.Equal(t, "prod", cfg.Default)
+	require.Equal(t, []string{"broken", "lab", "prod"}, cfg.Names())
+
+	p, err := cfg.Profile("")
+	require.NoError(t, err)
+	require.Equal(t, []string{"https://10.0.0.10:8006", "https://10.0.0.11:8006"}, p.URLs)
+	require.Equal(t, "automation@pve!ci", p.TokenID)
+
+	_, err = cfg.Profile("staging")
+	require.ErrorContains(t, err, "available clusters: broken, lab, prod")
+
+	cfg, err = LoadConfig("testdata/config/clusters.json")
+	require.NoError(t, err)
+	p, err = cfg.Profile("")
+	require.NoError(t, err)
+	require.Equal(t, "https://192.168.1.5:8006", p.URL)
+	require.True(t, p.Insecure)
+
+	_, err = LoadConfig("testdata/config/missing.yaml")
+	require.Error(t, err)
+	_, err = LoadConfig("testdata/tasks/get_task_log.json")
+	require.Error(t, err)
+}
+
+func TestNewClientFromConfig(t *testing.T) {
+	c, err := NewClientFromConfig("testdata/config/clusters.yaml", "")
+	require.NoError(t, err)
+	require.Equal(t, StaticCredentials("automation@pve!ci", "00000000-0000-0000-0000-000000000000"), c.credentials)
+	require.Equal(t, 2, c.endpoints.size())
+
+	c, err = NewClientFromConfig("testdata/config/clusters.yaml", "lab")
+	require.NoError(t, err)
+	require.Equal(t, "root@pam", c.username)
+	require.Equal(t, "https://192.168.1.5:8006/api2/json/", c.ActiveEndpoint())
+	require.Equal(t, []string{"AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB:AB"}, c.TLSFingerprints())
+
+	_, err = NewClientFromConfig("testdata/config/clusters.yaml", "broken")
+	require.Error(t, err)
+
+	c, err = NewClientFromConfig("testdata/config/clusters.json", "lab")
+	require.NoError(t, err)
+	require.Equal(t, StaticCredentials("root@pam!lab", "11111111-1111-1111-1111-111111111111"), c.credentials)
+}
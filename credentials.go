@@ -0,0 +1,156 @@
+package proxmox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileCredentialsCheckInterval is how often FileCredentials checks its file for changes
+const fileCredentialsCheckInterval = time.Second
+
+// Credentials are the API token a request is authenticated with
+type Credentials struct {
+	// TokenID is the identifier of the API token, e.g. root@pam!automation
+	TokenID string
+
+	// Secret is the token secret
+	Secret string
+}
+
+// CredentialProvider provides the API token credentials of a client. It's consulted for every request,
+// so a token can be rotated without creating a new client. Implementations must be safe for concurrent use.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialProviderFunc adapts a function to a CredentialProvider, e.g. to fetch tokens from a secret store.
+// The function is called for every request and must be safe for concurrent use.
+type CredentialProviderFunc func(ctx context.Context) (Credentials, error)
+
+// Credentials implements the CredentialProvider interface for CredentialProviderFunc
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// staticCredentials is a CredentialProvider of credentials that never change
+type staticCredentials Credentials
+
+// Credentials implements the CredentialProvider interface for staticCredentials
+func (s staticCredentials) Credentials(context.Context) (Credentials, error) {
+	return Credentials(s), nil
+}
+
+// StaticCredentials returns a CredentialProvider that always provides the given API token
+func StaticCredentials(tokenID, secret string) CredentialProvider {
+	return staticCredentials{TokenID: tokenID, Secret: secret}
+}
+
+// FileCredentials is a CredentialProvider that reads an API token from a file, and rereads it when the file changes.
+// The file contains the token ID and secret in the format of the Authorization header, e.g.
+// "root@pam!automation=00000000-0000-0000-0000-000000000000". The file is checked for changes at most once a second.
+// If rereading the file fails, e.g. while it's being replaced, the previous token is provided.
+type FileCredentials struct {
+	path string
+
+	mu          sync.Mutex
+	credentials Credentials
+	modTime     time.Time
+	size        int64
+	checked     time.Time
+}
+
+// NewFileCredentials returns a FileCredentials for the file at path. It returns an error if the file can't be read.
+func NewFileCredentials(path string) (*FileCredentials, error) {
+	f := &FileCredentials{path: path}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Credentials implements the CredentialProvider interface for FileCredentials
+func (f *FileCredentials) Credentials(context.Context) (Credentials, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if time.Since(f.checked) >= fileCredentialsCheckInterval {
+		f.checked = time.Now()
+		if fi, err := os.Stat(f.path); err == nil && (!fi.ModTime().Equal(f.modTime) || fi.Size() != f.size) {
+			// Keep the previous token if the file is being replaced
+			_ = f.loadLocked()
+		}
+	}
+
+	return f.credentials, nil
+}
+
+// load reads the token from the file
+func (f *FileCredentials) load() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.loadLocked()
+}
+
+// loadLocked is like load, but the caller must hold f.mu
+func (f *FileCredentials) loadLocked() error {
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("error reading Proxmox API token: %w", err)
+	}
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("error reading Proxmox API token: %w", err)
+	}
+
+	creds, err := parseToken(string(b))
+	if err != nil {
+		return fmt.Errorf("error reading Proxmox API token from %s: %w", f.path, err)
+	}
+
+	f.credentials = creds
+	f.modTime = fi.ModTime()
+	f.size = fi.Size()
+	f.checked = time.Now()
+
+	return nil
+}
+
+// parseToken parses an API token in the format tokenID=secret
+func parseToken(s string) (Credentials, error) {
+	tokenID, secret, ok := strings.Cut(strings.TrimSpace(s), "=")
+	if !ok || tokenID == "" || secret == "" {
+		return Credentials{}, errors.New("API token must have the format tokenID=secret")
+	}
+	return Credentials{TokenID: tokenID, Secret: secret}, nil
+}
+
+// NewClientWithCredentials returns a new Proxmox API client that authenticates with the API token of a CredentialProvider,
+// which is consulted for every request
+func NewClientWithCredentials(provider CredentialProvider, options ...ClientOptionFunc) (*Client, error) {
+	if provider == nil {
+		return nil, errors.New("can not create Proxmox API client without a credential provider")
+	}
+
+	c := &Client{
+		credentials: provider,
+	}
+
+	return newClient(c, options...)
+}
+
+// tokenCredentials returns the API token for a request
+func (c *Client) tokenCredentials(ctx context.Context) (Credentials, error) {
+	creds, err := c.credentials.Credentials(ctx)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("error getting Proxmox API token: %w", err)
+	}
+	if creds.TokenID == "" || creds.Secret == "" {
+		return Credentials{}, errors.New("error getting Proxmox API token: empty token ID or secret")
+	}
+	return creds, nil
+}
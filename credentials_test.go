@@ -0,0 +1,161 @@
+package proxmox
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupWithCredentials(t *testing.T, provider CredentialProvider) (*httptest.Server, *Client, *atomic.Value) {
+	var auth atomic.Value
+	auth.Store("")
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.Store(r.Header.Get("Authorization"))
+		_, _ = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+	}))
+
+	httpClient := http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+		},
+	}
+
+	client, err := NewClientWithCredentials(provider,
+		WithBaseURL(fmt.Sprintf("%s/", server.URL)),
+		WithHTTPClient(&httpClient),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return server, client, &auth
+}
+
+func TestNewClientWithCredentials(t *testing.T) {
+	_, err := NewClientWithCredentials(nil)
+	require.Error(t, err)
+
+	server, client, auth := setupWithCredentials(t, StaticCredentials("root@pam!test", "secret"))
+	defer teardown(server)
+
+	_, _, err = client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Equal(t, "PVEAPIToken=root@pam!test=secret", auth.Load())
+}
+
+func TestCredentialProviderFunc(t *testing.T) {
+	var calls int32
+	provider := CredentialProviderFunc(func(ctx context.Context) (Credentials, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 3 {
+			return Credentials{}, errors.New("secret store unavailable")
+		}
+		return Credentials{TokenID: "root@pam!test", Secret: fmt.Sprintf("secret-%d", n)}, nil
+	})
+	server, client, auth := setupWithCredentials(t, provider)
+	defer teardown(server)
+
+	// The provider is consulted for every request
+	_, _, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Equal(t, "PVEAPIToken=root@pam!test=secret-1", auth.Load())
+	_, _, err = client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Equal(t, "PVEAPIToken=root@pam!test=secret-2", auth.Load())
+
+	// Provider errors fail the request without sending it
+	auth.Store("")
+	_, _, err = client.Nodes.GetNodes()
+	require.ErrorContains(t, err, "secret store unavailable")
+	require.Equal(t, "", auth.Load())
+
+	// Concurrent requests
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _ = client.Nodes.GetNodes()
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(13), atomic.LoadInt32(&calls))
+
+	// Empty credentials are rejected
+	server, client, _ = setupWithCredentials(t, CredentialProviderFunc(func(ctx context.Context) (Credentials, error) {
+		return Credentials{}, nil
+	}))
+	defer teardown(server)
+	_, _, err = client.Nodes.GetNodes()
+	require.Error(t, err)
+}
+
+func TestFileCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+
+	_, err := NewFileCredentials(path)
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("not a token\n"), 0o600))
+	_, err = NewFileCredentials(path)
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("root@pam!test=first\n"), 0o600))
+	creds, err := NewFileCredentials(path)
+	require.NoError(t, err)
+
+	server, client, auth := setupWithCredentials(t, creds)
+	defer teardown(server)
+
+	_, _, err = client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Equal(t, "PVEAPIToken=root@pam!test=first", auth.Load())
+
+	// Rotate the token, skipping the check interval
+	require.NoError(t, os.WriteFile(path, []byte("root@pam!test=second\n"), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	creds.mu.Lock()
+	creds.checked = time.Time{}
+	creds.mu.Unlock()
+
+	_, _, err = client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Equal(t, "PVEAPIToken=root@pam!test=second", auth.Load())
+
+	// A missing or broken file keeps the previous token
+	require.NoError(t, os.WriteFile(path, []byte("broken"), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Minute)))
+	creds.mu.Lock()
+	creds.checked = time.Time{}
+	creds.mu.Unlock()
+
+	c, err := creds.Credentials(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, Credentials{TokenID: "root@pam!test", Secret: "second"}, c)
+}
+
+func TestProfile_TokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("root@pam!file=secret\n"), 0o600))
+
+	t.Setenv(EnvTokenFile, path)
+	c, err := NewClientFromEnv()
+	require.NoError(t, err)
+
+	creds, err := c.credentials.Credentials(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, Credentials{TokenID: "root@pam!file", Secret: "secret"}, creds)
+}
@@ -0,0 +1,462 @@
+package proxmox
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// netModels are the emulated network cards Proxmox accepts as the key of a NIC's MAC address, like "virtio=BC:24:11:2E:7A:10"
+var netModels = map[string]bool{
+	"e1000": true, "e1000-82540em": true, "e1000-82544gc": true, "e1000-82545em": true, "e1000e": true,
+	"i82551": true, "i82557b": true, "i82559er": true, "ne2k_isa": true, "ne2k_pci": true,
+	"pcnet": true, "rtl8139": true, "virtio": true, "vmxnet3": true,
+}
+
+// netFields are the NIC properties with a NetDevice field, except the model and MAC address
+var netFields = map[string]bool{
+	"bridge": true, "firewall": true, "link_down": true, "tag": true, "trunks": true, "rate": true, "mtu": true, "queues": true,
+}
+
+// diskFields are the disk properties with a DiskDevice field, except the volume
+var diskFields = map[string]bool{
+	"media": true, "size": true, "format": true, "cache": true, "aio": true, "discard": true, "iothread": true, "ssd": true,
+	"ro": true, "backup": true, "replicate": true, "serial": true,
+}
+
+// NetDevice is a network device of a VM, parsed from the property string of a netN option like
+// "virtio=BC:24:11:2E:7A:10,bridge=vmbr0,firewall=1,tag=20"
+type NetDevice struct {
+	Model      string // Emulated network card, like "virtio" or "e1000"
+	MACAddress string // Proxmox generates one if it's empty
+	Bridge     string // Bridge the device is attached to, like "vmbr0"
+	Firewall   *bool  // Use the Proxmox firewall
+	LinkDown   *bool  // Disconnect the device
+	Tag        int    // VLAN tag, 0 if untagged
+	Trunks     string // VLAN IDs allowed through the device, like "10;20;30"
+	Rate       string // Rate limit in MB/s, like "12.5"
+	MTU        int    // MTU, only for virtio devices
+	Queues     int    // Number of packet queues, only for virtio devices
+
+	// Other contains the properties without a field keyed by name, so they're kept by String
+	Other map[string]string
+
+	// order contains the property names in the order they were parsed, so String keeps it
+	order []string
+
+	// modelKey and macKey record whether the model and MAC address were parsed from model= and macaddr= properties
+	// instead of the "virtio=BC:24:11:2E:7A:10" form, and text the parsed text of the other fields, so String keeps their form
+	modelKey bool
+	macKey   bool
+	text     map[string]propertyText
+}
+
+// ParseNetDevice parses the property string of a netN option
+func ParseNetDevice(s string) (*NetDevice, error) {
+	def, props, err := parsePropertyString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NIC %q: %w", s, err)
+	}
+
+	d := &NetDevice{Model: def}
+	for _, p := range props {
+		d.order = append(d.order, p.key)
+		switch p.key {
+		case "model":
+			d.Model, d.modelKey = p.value, true
+		case "macaddr":
+			d.MACAddress, d.macKey = p.value, true
+		case "bridge":
+			d.Bridge = p.value
+		case "firewall":
+			d.Firewall, err = parseFlag(p.value)
+		case "link_down":
+			d.LinkDown, err = parseFlag(p.value)
+		case "tag":
+			d.Tag, err = strconv.Atoi(p.value)
+		case "trunks":
+			d.Trunks = p.value
+		case "rate":
+			d.Rate = p.value
+		case "mtu":
+			d.MTU, err = strconv.Atoi(p.value)
+		case "queues":
+			d.Queues, err = strconv.Atoi(p.value)
+		default:
+			if !netModels[p.key] {
+				d.Other = setOther(d.Other, p)
+				continue
+			}
+			if d.Model != "" {
+				return nil, fmt.Errorf("invalid NIC %q: more than one model", s)
+			}
+			d.Model, d.MACAddress = p.key, p.value
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid NIC %q: %s: %w", s, p.key, err)
+		}
+	}
+	if d.Model == "" {
+		return nil, fmt.Errorf("invalid NIC %q: no model", s)
+	}
+	d.text = fieldText(props, d.fields(), netFields)
+
+	return d, nil
+}
+
+// String returns the property string of the device in the format Proxmox uses, like "virtio=BC:24:11:2E:7A:10,bridge=vmbr0".
+// Properties keep the order they were parsed in, others are sorted by name. Unchanged properties keep the form they were
+// parsed in, like "model=virtio,macaddr=BC:24:11:2E:7A:10", "firewall=on" or "tag=0", changed ones are formatted like Proxmox does.
+func (d *NetDevice) String() string {
+	values := copyOther(d.Other)
+	fields := d.fields()
+	keepText(fields, d.text)
+	for key, value := range fields {
+		values[key] = value
+	}
+
+	first := d.Model
+	if d.modelKey {
+		first = ""
+		values["model"] = d.Model
+	}
+	if d.MACAddress != "" {
+		if d.modelKey || d.macKey {
+			values["macaddr"] = d.MACAddress
+		} else {
+			first += "=" + d.MACAddress
+		}
+	}
+
+	return formatPropertyString(first, values, d.order)
+}
+
+// fields returns the properties of the fields except the model and MAC address, formatted like Proxmox does
+func (d *NetDevice) fields() map[string]string {
+	values := map[string]string{}
+	setString(values, "bridge", d.Bridge)
+	setFlag(values, "firewall", d.Firewall)
+	setFlag(values, "link_down", d.LinkDown)
+	setInt(values, "tag", d.Tag)
+	setString(values, "trunks", d.Trunks)
+	setString(values, "rate", d.Rate)
+	setInt(values, "mtu", d.MTU)
+	setInt(values, "queues", d.Queues)
+	return values
+}
+
+// DiskDevice is a disk or CD-ROM drive of a VM, parsed from the property string of an ideN, sataN, scsiN or virtioN option
+// like "local-lvm:vm-100-disk-0,discard=on,iothread=1,size=32G"
+type DiskDevice struct {
+	// Volume is the volume ID of the disk, like "local-lvm:vm-100-disk-0". When creating disks it can be a storage and
+	// a size in GiB to allocate a new volume, like "local-lvm:32". CD-ROM drives can use "none" or "cdrom" instead.
+	Volume string
+
+	Media     string // Either "disk" or "cdrom", Proxmox defaults to "disk"
+	Size      string // Size of the volume, like "32G". Proxmox sets this, resize disks with the resize endpoint instead.
+	Format    string // Volume format, like "raw" or "qcow2"
+	Cache     string // Cache mode, like "none" or "writeback"
+	AIO       string // Asynchronous I/O mode, like "io_uring" or "native"
+	Discard   string // Either "on" to pass discard requests to the storage, or "ignore"
+	IOThread  *bool  // Use a dedicated I/O thread, only for virtio and scsi disks with the virtio-scsi-single controller
+	SSD       *bool  // Present the disk as an SSD to the guest
+	ReadOnly  *bool  // Attach the disk read-only
+	Backup    *bool  // Include the disk in backups, Proxmox defaults to true
+	Replicate *bool  // Include the disk in replication jobs, Proxmox defaults to true
+	Serial    string // Serial number reported to the guest
+
+	// Other contains the properties without a field keyed by name, so they're kept by String
+	Other map[string]string
+
+	// order contains the property names in the order they were parsed, so String keeps it
+	order []string
+
+	// volumeKey records whether the volume was parsed from a file= property, and text the parsed text of the other fields,
+	// so String keeps their form
+	volumeKey bool
+	text      map[string]propertyText
+}
+
+// ParseDiskDevice parses the property string of an ideN, sataN, scsiN or virtioN option
+func ParseDiskDevice(s string) (*DiskDevice, error) {
+	def, props, err := parsePropertyString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid disk %q: %w", s, err)
+	}
+
+	d := &DiskDevice{Volume: def}
+	for _, p := range props {
+		d.order = append(d.order, p.key)
+		switch p.key {
+		case "file":
+			d.Volume, d.volumeKey = p.value, true
+		case "media":
+			d.Media = p.value
+		case "size":
+			d.Size = p.value
+		case "format":
+			d.Format = p.value
+		case "cache":
+			d.Cache = p.value
+		case "aio":
+			d.AIO = p.value
+		case "discard":
+			d.Discard = p.value
+		case "iothread":
+			d.IOThread, err = parseFlag(p.value)
+		case "ssd":
+			d.SSD, err = parseFlag(p.value)
+		case "ro":
+			d.ReadOnly, err = parseFlag(p.value)
+		case "backup":
+			d.Backup, err = parseFlag(p.value)
+		case "replicate":
+			d.Replicate, err = parseFlag(p.value)
+		case "serial":
+			d.Serial = p.value
+		default:
+			d.Other = setOther(d.Other, p)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid disk %q: %s: %w", s, p.key, err)
+		}
+	}
+	if d.Volume == "" {
+		return nil, fmt.Errorf("invalid disk %q: no volume", s)
+	}
+	d.text = fieldText(props, d.fields(), diskFields)
+
+	return d, nil
+}
+
+// String returns the property string of the disk in the format Proxmox uses, like "local-lvm:vm-100-disk-0,size=32G".
+// Properties keep the order they were parsed in, others are sorted by name. Unchanged properties keep the form they were
+// parsed in, like "file=local-lvm:vm-100-disk-0" or "ssd=on", changed ones are formatted like Proxmox does.
+func (d *DiskDevice) String() string {
+	values := copyOther(d.Other)
+	fields := d.fields()
+	keepText(fields, d.text)
+	for key, value := range fields {
+		values[key] = value
+	}
+
+	first := d.Volume
+	if d.volumeKey {
+		first = ""
+		values["file"] = d.Volume
+	}
+
+	return formatPropertyString(first, values, d.order)
+}
+
+// fields returns the properties of the fields except the volume, formatted like Proxmox does
+func (d *DiskDevice) fields() map[string]string {
+	values := map[string]string{}
+	setString(values, "media", d.Media)
+	setString(values, "size", d.Size)
+	setString(values, "format", d.Format)
+	setString(values, "cache", d.Cache)
+	setString(values, "aio", d.AIO)
+	setString(values, "discard", d.Discard)
+	setFlag(values, "iothread", d.IOThread)
+	setFlag(values, "ssd", d.SSD)
+	setFlag(values, "ro", d.ReadOnly)
+	setFlag(values, "backup", d.Backup)
+	setFlag(values, "replicate", d.Replicate)
+	setString(values, "serial", d.Serial)
+	return values
+}
+
+// Storage returns the storage of the disk's volume, like "local-lvm", or an empty string for CD-ROM drives without
+// media and disks passed through by path
+func (d *DiskDevice) Storage() string {
+	storage, _, ok := strings.Cut(d.Volume, ":")
+	if !ok || strings.HasPrefix(d.Volume, "/") {
+		return ""
+	}
+	return storage
+}
+
+// QemuDisks parses the disks of a VM config, like QemuConfigValues or IndexedOptions, keyed by option name like "scsi0".
+// It includes CD-ROM drives, but not the EFI disk, TPM state or unused volumes.
+func QemuDisks(config map[string]string) (map[string]*DiskDevice, error) {
+	disks := map[string]*DiskDevice{}
+	for key, value := range config {
+		prefix, _, ok := splitIndexedKey(key)
+		if !ok || (prefix != "ide" && prefix != "sata" && prefix != "scsi" && prefix != "virtio") {
+			continue
+		}
+		d, err := ParseDiskDevice(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		disks[key] = d
+	}
+	return disks, nil
+}
+
+// QemuNICs parses the network devices of a VM config, like QemuConfigValues or IndexedOptions, keyed by option name like "net0"
+func QemuNICs(config map[string]string) (map[string]*NetDevice, error) {
+	nics := map[string]*NetDevice{}
+	for key, value := range config {
+		if prefix, _, ok := splitIndexedKey(key); !ok || prefix != "net" {
+			continue
+		}
+		d, err := ParseNetDevice(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		nics[key] = d
+	}
+	return nics, nil
+}
+
+// property is a name and value of a property string
+type property struct {
+	key   string
+	value string
+}
+
+// parsePropertyString splits a property string like "local-lvm:32,discard=on" into the value without a name,
+// which is "local-lvm:32" in that example, and the named properties in their order
+func parsePropertyString(s string) (string, []property, error) {
+	if s == "" {
+		return "", nil, errors.New("empty property string")
+	}
+
+	var def string
+	var props []property
+	seen := map[string]bool{}
+	for _, item := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			if def != "" || item == "" {
+				return "", nil, fmt.Errorf("unexpected value %q", item)
+			}
+			def = item
+			continue
+		}
+		if key == "" {
+			return "", nil, fmt.Errorf("property without a name in %q", item)
+		}
+		if seen[key] {
+			return "", nil, fmt.Errorf("duplicate property %s", key)
+		}
+		seen[key] = true
+		props = append(props, property{key: key, value: value})
+	}
+	return def, props, nil
+}
+
+// formatPropertyString joins the value without a name, if any, and the named values into a property string,
+// with the names in order first and the remaining names sorted
+func formatPropertyString(first string, values map[string]string, order []string) string {
+	var items []string
+	if first != "" {
+		items = append(items, first)
+	}
+	for _, key := range order {
+		if value, ok := values[key]; ok {
+			items = append(items, key+"="+value)
+			delete(values, key)
+		}
+	}
+
+	rest := make([]string, 0, len(values))
+	for key := range values {
+		rest = append(rest, key)
+	}
+	sort.Strings(rest)
+	for _, key := range rest {
+		items = append(items, key+"="+values[key])
+	}
+
+	return strings.Join(items, ",")
+}
+
+// propertyText is the text a property with a field was parsed from, and the field's value formatted when it was parsed
+type propertyText struct {
+	text      string
+	formatted string
+}
+
+// fieldText records the text of the parsed properties that have a field, given the formatted values of the fields
+func fieldText(props []property, formatted map[string]string, fields map[string]bool) map[string]propertyText {
+	text := map[string]propertyText{}
+	for _, p := range props {
+		if fields[p.key] {
+			text[p.key] = propertyText{text: p.value, formatted: formatted[p.key]}
+		}
+	}
+	return text
+}
+
+// keepText replaces the formatted values of fields that are unchanged since they were parsed with their parsed text,
+// so "on" isn't rewritten to "1" and "tag=0" isn't dropped
+func keepText(values map[string]string, text map[string]propertyText) {
+	for key, t := range text {
+		if values[key] == t.formatted {
+			values[key] = t.text
+		}
+	}
+}
+
+// parseFlag parses a boolean property. Proxmox writes them as 0 or 1 but accepts the other forms too.
+func parseFlag(value string) (*bool, error) {
+	var b bool
+	switch strings.ToLower(value) {
+	case "1", "on", "yes", "true":
+		b = true
+	case "0", "off", "no", "false":
+		b = false
+	default:
+		return nil, fmt.Errorf("invalid boolean %q", value)
+	}
+	return &b, nil
+}
+
+// setOther adds a property to a map of properties without a field, creating the map if needed
+func setOther(other map[string]string, p property) map[string]string {
+	if other == nil {
+		other = map[string]string{}
+	}
+	other[p.key] = p.value
+	return other
+}
+
+// copyOther copies the properties without a field, so formatting a property string doesn't modify them
+func copyOther(other map[string]string) map[string]string {
+	values := make(map[string]string, len(other))
+	for k, v := range other {
+		values[k] = v
+	}
+	return values
+}
+
+// setString sets a property if the value isn't empty
+func setString(values map[string]string, key, value string) {
+	if value != "" {
+		values[key] = value
+	}
+}
+
+// setInt sets a property if the value isn't 0
+func setInt(values map[string]string, key string, value int) {
+	if value != 0 {
+		values[key] = strconv.Itoa(value)
+	}
+}
+
+// setFlag sets a boolean property to 0 or 1 if the value isn't nil
+func setFlag(values map[string]string, key string, value *bool) {
+	if value == nil {
+		return
+	}
+	if *value {
+		values[key] = "1"
+	} else {
+		values[key] = "0"
+	}
+}
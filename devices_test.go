@@ -0,0 +1,152 @@
+package proxmox
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetDevice(t *testing.T) {
+	d, err := ParseNetDevice("virtio=BC:24:11:2E:7A:10,bridge=vmbr0,firewall=1,tag=20")
+	require.NoError(t, err)
+	require.Equal(t, "virtio", d.Model)
+	require.Equal(t, "BC:24:11:2E:7A:10", d.MACAddress)
+	require.Equal(t, "vmbr0", d.Bridge)
+	require.True(t, *d.Firewall)
+	require.Nil(t, d.LinkDown)
+	require.Equal(t, 20, d.Tag)
+
+	d.Tag = 30
+	d.LinkDown = new(bool)
+	d.Queues = 4
+	require.Equal(t, "virtio=BC:24:11:2E:7A:10,bridge=vmbr0,firewall=1,tag=30,link_down=0,queues=4", d.String())
+
+	d = &NetDevice{Model: "e1000", Bridge: "vmbr1", MTU: 9000}
+	require.Equal(t, "e1000,bridge=vmbr1,mtu=9000", d.String())
+}
+
+func TestParseNetDeviceForms(t *testing.T) {
+	d, err := ParseNetDevice("model=virtio,macaddr=BC:24:11:2E:7A:10,bridge=vmbr0")
+	require.NoError(t, err)
+	require.Equal(t, "virtio", d.Model)
+	require.Equal(t, "BC:24:11:2E:7A:10", d.MACAddress)
+	require.Equal(t, "model=virtio,macaddr=BC:24:11:2E:7A:10,bridge=vmbr0", d.String())
+
+	d, err = ParseNetDevice("virtio,bridge=vmbr0")
+	require.NoError(t, err)
+	require.Equal(t, "virtio", d.Model)
+	require.Empty(t, d.MACAddress)
+
+	d.MACAddress = "BC:24:11:2E:7A:10"
+	require.Equal(t, "virtio=BC:24:11:2E:7A:10,bridge=vmbr0", d.String())
+
+	// Unchanged properties keep their form, changed ones are formatted like Proxmox does
+	d, err = ParseNetDevice("virtio,macaddr=BC:24:11:2E:7A:10,firewall=on,link_down=no,tag=0")
+	require.NoError(t, err)
+	require.True(t, *d.Firewall)
+	require.Zero(t, d.Tag)
+	require.Equal(t, "virtio,macaddr=BC:24:11:2E:7A:10,firewall=on,link_down=no,tag=0", d.String())
+
+	*d.Firewall = false
+	d.Tag = 20
+	require.Equal(t, "virtio,macaddr=BC:24:11:2E:7A:10,firewall=0,link_down=no,tag=20", d.String())
+
+	disk, err := ParseDiskDevice("file=local-lvm:vm-100-disk-0,ssd=on,iothread=1")
+	require.NoError(t, err)
+	require.Equal(t, "local-lvm:vm-100-disk-0", disk.Volume)
+	require.Equal(t, "file=local-lvm:vm-100-disk-0,ssd=on,iothread=1", disk.String())
+
+	disk.SSD = nil
+	require.Equal(t, "file=local-lvm:vm-100-disk-0,iothread=1", disk.String())
+}
+
+func TestDiskDevice(t *testing.T) {
+	d, err := ParseDiskDevice("local-lvm:vm-100-disk-0,size=32G,discard=on")
+	require.NoError(t, err)
+	require.Equal(t, "local-lvm:vm-100-disk-0", d.Volume)
+	require.Equal(t, "local-lvm", d.Storage())
+	require.Equal(t, "32G", d.Size)
+	require.Equal(t, "on", d.Discard)
+
+	d.Backup = new(bool)
+	d.Discard = ""
+	require.Equal(t, "local-lvm:vm-100-disk-0,size=32G,backup=0", d.String())
+
+	// Disks for new VMs can be built without parsing
+	iothread := true
+	disks := IndexedOptions{"scsi0": (&DiskDevice{Volume: "local-lvm:32", Discard: "on", IOThread: &iothread}).String()}
+	require.Equal(t, IndexedOptions{"scsi0": "local-lvm:32,discard=on,iothread=1"}, disks)
+
+	cdrom, err := ParseDiskDevice("none,media=cdrom")
+	require.NoError(t, err)
+	require.Equal(t, "cdrom", cdrom.Media)
+	require.Empty(t, cdrom.Storage())
+}
+
+func TestPropertyStringRoundTrip(t *testing.T) {
+	nics := []string{
+		"virtio=BC:24:11:2E:7A:10,bridge=vmbr0,firewall=1,tag=20",
+		"e1000=BC:24:11:5A:0C:31,bridge=vmbr1,link_down=1,mtu=1500,queues=2,rate=12.5,trunks=10;20;30",
+		"virtio=BC:24:11:2E:7A:10,tag=20,bridge=vmbr0",
+		"virtio=BC:24:11:2E:7A:10,bridge=vmbr0,future-option=x,firewall=0",
+	}
+	for _, s := range nics {
+		d, err := ParseNetDevice(s)
+		require.NoError(t, err, s)
+		require.Equal(t, s, d.String())
+	}
+
+	disks := []string{
+		"local-lvm:vm-100-disk-0,discard=on,iothread=1,size=32G",
+		"local-lvm:vm-100-disk-0,size=32G,discard=on",
+		"local-lvm:vm-100-cloudinit,media=cdrom",
+		"ceph:vm-100-disk-1,backup=0,cache=writeback,replicate=0,ssd=1,wwn=0x5000c500a1b2c3d4",
+		"/dev/disk/by-id/ata-WDC_WD40EFRX,backup=0,serial=WD-1234",
+	}
+	for _, s := range disks {
+		d, err := ParseDiskDevice(s)
+		require.NoError(t, err, s)
+		require.Equal(t, s, d.String())
+	}
+}
+
+func TestPropertyStringUnknownKeys(t *testing.T) {
+	d, err := ParseDiskDevice("local-lvm:vm-100-disk-0,size=32G,mbps_rd=100,iops_wr=500")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"mbps_rd": "100", "iops_wr": "500"}, d.Other)
+
+	d.Other["mbps_wr"] = "50"
+	require.Equal(t, "local-lvm:vm-100-disk-0,size=32G,mbps_rd=100,iops_wr=500,mbps_wr=50", d.String())
+}
+
+func TestPropertyStringInvalid(t *testing.T) {
+	for _, s := range []string{"", "bridge=vmbr0", "virtio,e1000=BC:24:11:2E:7A:10", "virtio,tag=x", "virtio,firewall=maybe", "virtio,bridge=vmbr0,bridge=vmbr1", "virtio,=1", "virtio,,tag=1"} {
+		_, err := ParseNetDevice(s)
+		require.Error(t, err, s)
+	}
+	for _, s := range []string{"", "size=32G", "local-lvm:32,other-volume", "local-lvm:32,ssd=2"} {
+		_, err := ParseDiskDevice(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestQemuDevices(t *testing.T) {
+	var r GetQemuConfigResponse
+	require.NoError(t, json.Unmarshal([]byte(fixture("nodes/get_qemu_config.json")), &r))
+
+	disks, err := QemuDisks(r.Data)
+	require.NoError(t, err)
+	require.Len(t, disks, 2)
+	require.Equal(t, "32G", disks["scsi0"].Size)
+	require.Equal(t, "cdrom", disks["ide2"].Media)
+
+	nics, err := QemuNICs(r.Data)
+	require.NoError(t, err)
+	require.Len(t, nics, 1)
+	require.Equal(t, "vmbr0", nics["net0"].Bridge)
+
+	nics, err = QemuNICs(IndexedOptions{"net0": "virtio,bridge=vmbr0", "net1": "bridge=vmbr1"})
+	require.ErrorContains(t, err, "net1")
+	require.Nil(t, nics)
+}
@@ -0,0 +1,355 @@
+package proxmox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultEndpointCooldown is how long an endpoint that failed is skipped before being tried again
+	defaultEndpointCooldown = 30 * time.Second
+
+	// endpointCheckTimeout is how long a background health check of the endpoints may take
+	endpointCheckTimeout = 10 * time.Second
+)
+
+// endpoint is one of the base URLs a client can send requests to
+type endpoint struct {
+	url       *url.URL
+	downUntil time.Time
+}
+
+// endpointPool holds the base URLs of a client, which fails over between them if there are several.
+// A client's pool is created with the client and not replaced afterwards, so it can be used without locking the client.
+type endpointPool struct {
+	mu        sync.Mutex
+	endpoints []*endpoint
+	active    int
+	cooldown  time.Duration
+
+	// interval is how often the endpoints are checked in the background, 0 disables background checks
+	interval  time.Duration
+	lastCheck time.Time
+	checking  bool
+}
+
+// newEndpointPool returns a pool with a single endpoint
+func newEndpointPool(u *url.URL) *endpointPool {
+	p := &endpointPool{cooldown: defaultEndpointCooldown}
+	p.add(u)
+	return p
+}
+
+// WithBaseURLs sets several URLs for API requests, typically one per cluster node.
+// Requests are sent to one endpoint at a time. When it can't be reached the client fails over to the next healthy endpoint,
+// and skips the failed endpoint until it recovers. All endpoints should share the same API path.
+// API path is applied automatically if unspecified.
+func WithBaseURLs(urlStrs ...string) ClientOptionFunc {
+	return func(c *Client) error {
+		if len(urlStrs) == 0 {
+			return errors.New("at least one base URL is required")
+		}
+
+		// Start from the first URL, then add the rest as failover endpoints
+		if err := c.setBaseURL(urlStrs[0]); err != nil {
+			return err
+		}
+		for _, urlStr := range urlStrs[1:] {
+			u, err := c.parseBaseURL(urlStr)
+			if err != nil {
+				return err
+			}
+			c.endpoints.add(u)
+		}
+
+		return nil
+	}
+}
+
+// WithEndpointCooldown sets how long an endpoint that could not be reached is skipped before it's tried again.
+// Only applies to clients with several base URLs.
+// Default: 30s
+func WithEndpointCooldown(d time.Duration) ClientOptionFunc {
+	return func(c *Client) error {
+		c.endpointCooldown = &d
+		return nil
+	}
+}
+
+// WithEndpointHealthCheck checks the health of every endpoint in the background, like CheckEndpoints does.
+// Checks run at most once per interval while the client sends requests, and right after an endpoint could not be reached,
+// so endpoints that recovered are used again and unreachable endpoints are skipped before a request has to wait for them.
+// Without this option endpoints are only checked when CheckEndpoints is called.
+// Only applies to clients with several base URLs.
+func WithEndpointHealthCheck(interval time.Duration) ClientOptionFunc {
+	return func(c *Client) error {
+		if interval <= 0 {
+			return errors.New("endpoint health check interval must be positive")
+		}
+		c.endpointCheckInterval = interval
+		return nil
+	}
+}
+
+// configureEndpoints applies the endpoint options of the client to its endpoints, once all options were applied
+func (c *Client) configureEndpoints() error {
+	if c.endpointCooldown == nil && c.endpointCheckInterval == 0 {
+		return nil
+	}
+	if c.endpoints.size() < 2 {
+		return errors.New("endpoint cooldown and health checks require multiple base URLs, set them with WithBaseURLs")
+	}
+
+	if c.endpointCooldown != nil {
+		c.endpoints.cooldown = *c.endpointCooldown
+	}
+	c.endpoints.interval = c.endpointCheckInterval
+	return nil
+}
+
+// add adds an endpoint to the pool unless an endpoint with the same scheme and host exists
+func (p *endpointPool) add(u *url.URL) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, ep := range p.endpoints {
+		if ep.url.Scheme == u.Scheme && ep.url.Host == u.Host {
+			return false
+		}
+	}
+	p.endpoints = append(p.endpoints, &endpoint{url: u})
+	return true
+}
+
+// current returns the endpoint requests should be sent to.
+// If every endpoint is down, the one that will recover the soonest is returned.
+func (p *endpointPool) current() *endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	soonest := p.active
+	for i := 0; i < len(p.endpoints); i++ {
+		idx := (p.active + i) % len(p.endpoints)
+		ep := p.endpoints[idx]
+		if !now.Before(ep.downUntil) {
+			p.active = idx
+			return ep
+		}
+		if ep.downUntil.Before(p.endpoints[soonest].downUntil) {
+			soonest = idx
+		}
+	}
+
+	return p.endpoints[soonest]
+}
+
+// markDown skips an endpoint until its cooldown passed
+func (p *endpointPool) markDown(ep *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ep.downUntil = time.Now().Add(p.cooldown)
+}
+
+// markUp marks an endpoint as healthy
+func (p *endpointPool) markUp(ep *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ep.downUntil = time.Time{}
+}
+
+// size returns the number of endpoints in the pool
+func (p *endpointPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.endpoints)
+}
+
+// startCheck reports whether a background health check is due, because the interval passed since the last check
+// or an endpoint just failed, and records that it started. Only one check runs at a time.
+func (p *endpointPool) startCheck(failed bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.interval <= 0 || p.checking || (!failed && time.Since(p.lastCheck) < p.interval) {
+		return false
+	}
+	p.checking = true
+	p.lastCheck = time.Now()
+	return true
+}
+
+// finishCheck records that a background health check finished
+func (p *endpointPool) finishCheck() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checking = false
+}
+
+// snapshot returns a copy of the endpoints in the pool
+func (p *endpointPool) snapshot() []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*endpoint(nil), p.endpoints...)
+}
+
+// ActiveEndpoint returns the base URL requests are currently sent to
+func (c *Client) ActiveEndpoint() string {
+	return c.endpoints.current().url.String()
+}
+
+// CheckEndpoints checks whether the pveproxy of every endpoint responds, and marks unreachable endpoints as down.
+// Any HTTP response counts as healthy, so no authentication is needed. It returns an error if no endpoint is healthy.
+// Clients with a single base URL are always considered healthy.
+// Use WithEndpointHealthCheck to have the client check its endpoints in the background.
+func (c *Client) CheckEndpoints(ctx context.Context) error {
+	if c.endpoints.size() < 2 {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	endpoints := c.endpoints.snapshot()
+	healthy := make([]bool, len(endpoints))
+	for i, ep := range endpoints {
+		wg.Add(1)
+		go func(i int, ep *endpoint) {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, ep.url.JoinPath("version").String(), nil)
+			if err != nil {
+				return
+			}
+			// Health checks aren't API requests, so they're sent without the client's middleware
+			resp, err := c.client.Do(req)
+			if err != nil {
+				c.endpoints.markDown(ep)
+				return
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			c.endpoints.markUp(ep)
+			healthy[i] = true
+		}(i, ep)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, ok := range healthy {
+		if ok {
+			return nil
+		}
+	}
+	return errors.New("no Proxmox API endpoint is reachable")
+}
+
+// checkEndpointsInBackground starts a health check of the endpoints if one is due, see WithEndpointHealthCheck
+func (c *Client) checkEndpointsInBackground(failed bool) {
+	if !c.endpoints.startCheck(failed) {
+		return
+	}
+	go func() {
+		defer c.endpoints.finishCheck()
+		ctx, cancel := context.WithTimeout(context.Background(), endpointCheckTimeout)
+		defer cancel()
+		_ = c.CheckEndpoints(ctx)
+	}()
+}
+
+// DiscoverEndpoints adds the IP address of every online cluster node from GetClusterStatus as a failover endpoint.
+// The scheme, port and path of the client's base URL are used for the discovered endpoints.
+// Since they're addressed by IP address, their TLS certificates must be valid for it. The certificates Proxmox issues
+// to cluster nodes are, once the cluster's CA is trusted with WithCAFile. Certificates only valid for a DNS name,
+// like those from ACME, fail verification, so pin them with WithTLSFingerprint and RefreshTLSFingerprints instead.
+// It's safe to call while the client sends other requests.
+func (c *Client) DiscoverEndpoints(ctx context.Context) error {
+	status, _, err := c.Cluster.GetClusterStatusWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("error discovering Proxmox API endpoints: %w", err)
+	}
+
+	for _, d := range status.Data {
+		if d.Type != "node" || d.IP == nil || *d.IP == "" || (d.Online != nil && *d.Online != 1) {
+			continue
+		}
+		u := *c.baseURL
+		if port := c.baseURL.Port(); port != "" {
+			u.Host = net.JoinHostPort(*d.IP, port)
+		} else {
+			u.Host = net.JoinHostPort(*d.IP, "8006")
+		}
+		c.endpoints.add(&u)
+	}
+
+	return nil
+}
+
+// roundTrip sends a request to the active endpoint, failing over to other endpoints when it can't be reached.
+// Each endpoint is sent a copy of the request, so the caller's request isn't modified.
+// The response body of the returned response is left open for the caller.
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	n := c.endpoints.size()
+	if n < 2 {
+		return c.transport.RoundTrip(req)
+	}
+	c.checkEndpointsInBackground(false)
+
+	for i := 0; ; i++ {
+		ep := c.endpoints.current()
+		epReq := req.Clone(req.Context())
+		epReq.URL.Scheme = ep.url.Scheme
+		epReq.URL.Host = ep.url.Host
+		epReq.Host = ""
+
+		// The body must be replayable to send it to another endpoint
+		if i > 0 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			epReq.Body = body
+		}
+
+		resp, err := c.transport.RoundTrip(epReq)
+		if err == nil {
+			c.endpoints.markUp(ep)
+			return resp, nil
+		}
+		if req.Context().Err() != nil || !endpointUnreachable(req, err) {
+			return resp, err
+		}
+
+		c.endpoints.markDown(ep)
+		c.checkEndpointsInBackground(true)
+		if i+1 >= n || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return resp, err
+		}
+	}
+}
+
+// endpointUnreachable reports whether an error means the endpoint could not be reached.
+// GET and HEAD requests fail over on any network error, but not on other errors, like a TLS fingerprint mismatch or
+// an error returned by middleware, which another endpoint would fail with as well. Other requests only fail over
+// when the connection could not be established, since they might have taken effect otherwise.
+func endpointUnreachable(req *http.Request, err error) bool {
+	// The HTTP client wraps every error in a *url.Error, which is a net.Error itself
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		var netErr net.Error
+		return errors.As(err, &netErr)
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
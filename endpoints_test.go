@@ -0,0 +1,268 @@
+package proxmox
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupEndpoints(t *testing.T, options ...ClientOptionFunc) (*httptest.Server, *httptest.Server, *Client) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+		if err != nil {
+			return
+		}
+	}
+	first := httptest.NewTLSServer(http.HandlerFunc(handler))
+	second := httptest.NewTLSServer(http.HandlerFunc(handler))
+
+	httpClient := http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+		},
+	}
+
+	client, err := NewClient("test-token-id", "test-token", append([]ClientOptionFunc{
+		WithBaseURLs(first.URL, second.URL),
+		WithHTTPClient(&httpClient),
+	}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return first, second, client
+}
+
+func TestWithBaseURLs(t *testing.T) {
+	_, err := NewClient("test-token-id", "test-token", WithBaseURLs())
+	require.Error(t, err)
+
+	_, err = NewClient("test-token-id", "test-token", WithEndpointCooldown(0))
+	require.Error(t, err)
+
+	c, err := NewClient("test-token-id", "test-token", WithBaseURLs("https://10.0.0.1:8006", "https://10.0.0.2:8006/", "https://10.0.0.1:8006/"))
+	require.NoError(t, err)
+	require.Equal(t, "https://10.0.0.1:8006/api2/json/", c.baseURL.String())
+	require.Equal(t, 2, c.endpoints.size())
+	require.Equal(t, "https://10.0.0.1:8006/api2/json/", c.ActiveEndpoint())
+
+	// A later single base URL replaces the endpoints
+	c, err = NewClient("test-token-id", "test-token", WithBaseURLs("https://10.0.0.1:8006", "https://10.0.0.2:8006"), WithBaseURL("https://10.0.0.3:8006"))
+	require.NoError(t, err)
+	require.Equal(t, 1, c.endpoints.size())
+	require.Equal(t, "https://10.0.0.3:8006/api2/json/", c.ActiveEndpoint())
+
+	_, err = NewClient("test-token-id", "test-token", WithEndpointHealthCheck(time.Minute))
+	require.Error(t, err)
+	_, err = NewClient("test-token-id", "test-token", WithBaseURLs("https://10.0.0.1:8006", "https://10.0.0.2:8006"), WithEndpointHealthCheck(0))
+	require.Error(t, err)
+
+	// Endpoint options don't depend on the order of the options
+	c, err = NewClient("test-token-id", "test-token", WithEndpointCooldown(time.Second), WithEndpointHealthCheck(time.Minute), WithBaseURLs("https://10.0.0.1:8006", "https://10.0.0.2:8006"))
+	require.NoError(t, err)
+	require.Equal(t, time.Second, c.endpoints.cooldown)
+	require.Equal(t, time.Minute, c.endpoints.interval)
+}
+
+func TestEndpointFailover(t *testing.T) {
+	first, second, client := setupEndpoints(t)
+	defer teardown(second)
+
+	r, _, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Len(t, r.Data, 3)
+	require.Equal(t, first.URL+"/api2/json/", client.ActiveEndpoint())
+
+	// Take the first endpoint down
+	teardown(first)
+
+	r, _, err = client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Len(t, r.Data, 3)
+	require.Equal(t, second.URL+"/api2/json/", client.ActiveEndpoint())
+
+	// Subsequent requests go to the second endpoint directly
+	r, _, err = client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Len(t, r.Data, 3)
+}
+
+func TestEndpointFailoverAllDown(t *testing.T) {
+	first, second, client := setupEndpoints(t)
+	teardown(first)
+	teardown(second)
+
+	_, _, err := client.Nodes.GetNodes()
+	require.Error(t, err)
+	require.Error(t, client.CheckEndpoints(context.Background()))
+}
+
+func TestCheckEndpoints(t *testing.T) {
+	first, second, client := setupEndpoints(t)
+	defer teardown(second)
+	teardown(first)
+
+	require.NoError(t, client.CheckEndpoints(context.Background()))
+	require.Equal(t, second.URL+"/api2/json/", client.ActiveEndpoint())
+}
+
+func TestDiscoverEndpoints(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/cluster/status", func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, fixture("clusters/get_cluster_status.json"))
+		if err != nil {
+			return
+		}
+	})
+
+	require.NoError(t, client.DiscoverEndpoints(context.Background()))
+
+	var urls []string
+	for _, ep := range client.endpoints.snapshot() {
+		urls = append(urls, ep.url.String())
+	}
+	require.Equal(t, []string{
+		server.URL + "/api2/json/",
+		"https://10.0.1.2:" + client.baseURL.Port() + "/api2/json/",
+		"https://10.0.1.3:" + client.baseURL.Port() + "/api2/json/",
+		"https://10.0.1.1:" + client.baseURL.Port() + "/api2/json/",
+	}, urls)
+	require.Equal(t, server.URL+"/api2/json/", client.ActiveEndpoint())
+}
+
+func TestDiscoverEndpointsTLS(t *testing.T) {
+	// The server's certificate is only valid for its DNS name, not for the IP address it's discovered by
+	server := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api2/json/cluster/status":
+			_, _ = fmt.Fprint(w, `{"data":[{"id":"node/srv1","name":"srv1","type":"node","ip":"127.0.0.1","online":1}]}`)
+		default:
+			_, _ = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+		}
+	}), "localhost")
+	defer teardown(server)
+	baseURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+
+	discover := func(options ...ClientOptionFunc) *Client {
+		c, err := NewClient("test-token-id", "test-token", append([]ClientOptionFunc{WithBaseURL(baseURL)}, options...)...)
+		require.NoError(t, err)
+		require.NoError(t, c.DiscoverEndpoints(context.Background()))
+		require.Equal(t, 2, c.endpoints.size())
+
+		// Send requests to the discovered endpoint
+		c.endpoints.markDown(c.endpoints.snapshot()[0])
+		require.Equal(t, strings.Replace(baseURL, "localhost", "127.0.0.1", 1)+"/api2/json/", c.ActiveEndpoint())
+		return c
+	}
+
+	// The hostname check fails for the discovered endpoint with CA verification
+	c := discover(WithCAFile(caFile))
+	_, _, err := c.Nodes.GetNodes()
+	require.Error(t, err)
+
+	// Pinned certificates are accepted on every endpoint
+	c = discover(WithTLSFingerprint(certificateFingerprint(server.Certificate())))
+	_, _, err = c.Nodes.GetNodes()
+	require.NoError(t, err)
+}
+
+func TestEndpointFailoverNetworkErrorsOnly(t *testing.T) {
+	first, second, client := setupEndpoints(t)
+	defer teardown(first)
+	defer teardown(second)
+
+	// Errors that aren't network errors would happen with every endpoint
+	var attempts int32
+	transport := client.client.Transport
+	client.client.Transport = RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&attempts, 1)
+		return nil, errors.New("certificate fingerprint mismatch")
+	})
+	_, _, err := client.Nodes.GetNodes()
+	require.Error(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	require.Equal(t, first.URL+"/api2/json/", client.ActiveEndpoint())
+
+	client.client.Transport = transport
+	_, _, err = client.Nodes.GetNodes()
+	require.NoError(t, err)
+}
+
+func TestEndpointFailoverKeepsRequest(t *testing.T) {
+	first, second, client := setupEndpoints(t)
+	defer teardown(second)
+	teardown(first)
+
+	req, err := client.NewRequest(http.MethodGet, "nodes", nil)
+	require.NoError(t, err)
+	u := req.URL.String()
+	_, err = client.Do(req, nil)
+	require.NoError(t, err)
+	require.Equal(t, u, req.URL.String())
+}
+
+func TestEndpointHealthCheck(t *testing.T) {
+	first, second, client := setupEndpoints(t, WithEndpointHealthCheck(time.Millisecond))
+	defer teardown(first)
+	defer teardown(second)
+
+	// The first endpoint recovers from a failure without waiting for its cooldown
+	down := client.endpoints.snapshot()[0]
+	client.endpoints.markDown(down)
+	require.Equal(t, second.URL+"/api2/json/", client.ActiveEndpoint())
+
+	time.Sleep(5 * time.Millisecond)
+	_, _, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		client.endpoints.mu.Lock()
+		defer client.endpoints.mu.Unlock()
+		return down.downUntil.IsZero()
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestDiscoverEndpointsConcurrent(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/cluster/status", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, fixture("clusters/get_cluster_status.json"))
+	})
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+	})
+
+	// Discovering endpoints while requests are in flight doesn't race with them
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := client.Nodes.GetNodes()
+			require.NoError(t, err)
+		}()
+	}
+	require.NoError(t, client.DiscoverEndpoints(context.Background()))
+	wg.Wait()
+	require.Equal(t, 4, client.endpoints.size())
+}
@@ -0,0 +1,160 @@
+package proxmox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// APIError is returned when the Proxmox API responds with a non-2xx status code
+type APIError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+
+	// Message is the error message Proxmox returned, which it usually puts in the status line
+	Message string
+
+	// Errors contains the per-parameter errors Proxmox returns for invalid request parameters, keyed by parameter name
+	Errors map[string]string
+
+	// Method is the HTTP method of the failed request
+	Method string
+
+	// Path is the URL path of the failed request
+	Path string
+
+	// Body is the raw response body
+	Body []byte
+}
+
+// Error implements the error interface for APIError
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s: %d", e.Method, e.Path, e.StatusCode)
+	if e.Message != "" {
+		fmt.Fprintf(&sb, " %s", e.Message)
+	}
+
+	// Sort parameter errors so the message is deterministic
+	params := make([]string, 0, len(e.Errors))
+	for p := range e.Errors {
+		params = append(params, p)
+	}
+	sort.Strings(params)
+	for _, p := range params {
+		fmt.Fprintf(&sb, "; %s: %s", p, e.Errors[p])
+	}
+
+	return sb.String()
+}
+
+// apiErrorBody contains the fields Proxmox may set in the body of an error response
+type apiErrorBody struct {
+	Message string            `json:"message"`
+	Errors  map[string]string `json:"errors"`
+}
+
+// newAPIError creates an APIError from a non-2xx response and its already read body
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       body,
+	}
+	if resp.Request != nil {
+		e.Method = resp.Request.Method
+		e.Path = resp.Request.URL.Path
+	}
+
+	var b apiErrorBody
+	if err := json.Unmarshal(body, &b); err == nil {
+		e.Message = strings.TrimSpace(b.Message)
+		e.Errors = b.Errors
+	}
+
+	// Proxmox puts the error message in the reason phrase of the status line, e.g. "500 VM 100 is locked (backup)"
+	if e.Message == "" {
+		e.Message = strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprintf("%d", resp.StatusCode)))
+	}
+
+	// Fall back to the raw body if it wasn't JSON
+	if e.Message == "" && !json.Valid(body) {
+		e.Message = strings.TrimSpace(string(body))
+	}
+
+	return e
+}
+
+// asAPIError returns the APIError in err's chain, if any
+func asAPIError(err error) (*APIError, bool) {
+	var e *APIError
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// messageContains reports whether the lowercased API error message contains any of the substrings
+func (e *APIError) messageContains(substrs ...string) bool {
+	msg := strings.ToLower(e.Message)
+	for _, s := range substrs {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// notFoundMessage matches the messages Proxmox responds with for missing resources, like
+// "Configuration file 'nodes/srv1/qemu-server/100.conf' does not exist", "storage 'nfs' does not exist" or "no such VM ('100')"
+var notFoundMessage = regexp.MustCompile(`(?i)^((configuration file|storage|snapshot|pool|user|group|role|realm|domain|volume|token) '[^']*' does not exist|no such (vm|ct|user|group|pool|role|realm|domain|snapshot|storage|volume|token) |unable to find configuration file for vm)`)
+
+// IsNotFound reports whether err is an APIError for a resource that does not exist.
+// Proxmox often reports missing guests and config files with a 500 status, so the messages of those are checked as well.
+func IsNotFound(err error) bool {
+	e, ok := asAPIError(err)
+	if !ok {
+		return false
+	}
+	return e.StatusCode == http.StatusNotFound || (e.StatusCode == http.StatusInternalServerError && notFoundMessage.MatchString(e.Message))
+}
+
+// IsPermissionDenied reports whether err is an APIError caused by missing authentication or privileges
+func IsPermissionDenied(err error) bool {
+	e, ok := asAPIError(err)
+	if !ok {
+		return false
+	}
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden || e.messageContains("permission check failed")
+}
+
+// IsConfigLocked reports whether err is an APIError caused by a locked guest configuration, such as during a backup or migration
+func IsConfigLocked(err error) bool {
+	e, ok := asAPIError(err)
+	if !ok {
+		return false
+	}
+	return e.messageContains("is locked", "can't lock file")
+}
+
+// IsConfigConflict reports whether err is an APIError caused by a configuration change with a digest that no longer matches,
+// because the configuration was changed by someone else since it was read
+func IsConfigConflict(err error) bool {
+	e, ok := asAPIError(err)
+	if !ok {
+		return false
+	}
+	return e.messageContains("detected modified configuration", "file changed by other user")
+}
+
+// IsTimeout reports whether err is an APIError caused by a timeout on the Proxmox side
+func IsTimeout(err error) bool {
+	e, ok := asAPIError(err)
+	if !ok {
+		return false
+	}
+	return e.StatusCode == http.StatusGatewayTimeout || e.StatusCode == 596 || e.messageContains("got timeout", "timed out")
+}
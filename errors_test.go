@@ -0,0 +1,73 @@
+package proxmox
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIError(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/100/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, err := fmt.Fprint(w, `{"data":null,"errors":{"vmid":"invalid format - value does not look like a valid VM ID"}}`)
+		if err != nil {
+			return
+		}
+	})
+
+	_, resp, err := client.Nodes.GetQemuSnapshots("srv1", 100)
+	require.Error(t, err)
+	require.NotNil(t, resp)
+
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	require.Equal(t, http.MethodGet, apiErr.Method)
+	require.Equal(t, "/api2/json/nodes/srv1/qemu/100/snapshot", apiErr.Path)
+	require.Equal(t, "Bad Request", apiErr.Message)
+	require.Equal(t, map[string]string{"vmid": "invalid format - value does not look like a valid VM ID"}, apiErr.Errors)
+	require.Equal(t, "GET /api2/json/nodes/srv1/qemu/100/snapshot: 400 Bad Request; vmid: invalid format - value does not look like a valid VM ID", apiErr.Error())
+}
+
+func TestAPIErrorClassification(t *testing.T) {
+	tests := []struct {
+		err              error
+		notFound         bool
+		permissionDenied bool
+		configLocked     bool
+		configConflict   bool
+		timeout          bool
+	}{
+		{err: &APIError{StatusCode: http.StatusNotFound}, notFound: true},
+		{err: &APIError{StatusCode: http.StatusInternalServerError, Message: "Configuration file 'nodes/srv1/qemu-server/100.conf' does not exist"}, notFound: true},
+		{err: &APIError{StatusCode: http.StatusInternalServerError, Message: "storage 'nfs-backup' does not exist"}, notFound: true},
+		{err: &APIError{StatusCode: http.StatusInternalServerError, Message: "no such VM ('100')"}, notFound: true},
+		{err: &APIError{StatusCode: http.StatusInternalServerError, Message: "command 'lvs' failed: open /dev/pve: No such file or directory"}},
+		{err: &APIError{StatusCode: http.StatusInternalServerError, Message: "unable to open file '/etc/pve/nodes/srv1/x' - no such file or directory"}},
+		{err: &APIError{StatusCode: http.StatusBadRequest, Message: "snapshot 'before-upgrade' does not exist"}},
+		{err: &APIError{StatusCode: http.StatusUnauthorized, Message: "authentication failure"}, permissionDenied: true},
+		{err: &APIError{StatusCode: http.StatusForbidden, Message: "Permission check failed (/vms/100, VM.PowerMgmt)"}, permissionDenied: true},
+		{err: &APIError{StatusCode: http.StatusInternalServerError, Message: "VM is locked (backup)"}, configLocked: true},
+		{err: &APIError{StatusCode: http.StatusInternalServerError, Message: "can't lock file '/var/lock/qemu-server/lock-100.conf' - got timeout"}, configLocked: true, timeout: true},
+		{err: &APIError{StatusCode: http.StatusInternalServerError, Message: "detected modified configuration - file changed by other user? Try again."}, configConflict: true},
+		{err: &APIError{StatusCode: 596, Message: "Connection timed out"}, timeout: true},
+		{err: fmt.Errorf("wrapped: %w", &APIError{StatusCode: http.StatusNotFound}), notFound: true},
+		{err: errors.New("VM 100 does not exist")},
+		{err: nil},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.notFound, IsNotFound(test.err), test.err)
+		require.Equal(t, test.permissionDenied, IsPermissionDenied(test.err), test.err)
+		require.Equal(t, test.configLocked, IsConfigLocked(test.err), test.err)
+		require.Equal(t, test.configConflict, IsConfigConflict(test.err), test.err)
+		require.Equal(t, test.timeout, IsTimeout(test.err), test.err)
+	}
+}
@@ -0,0 +1,11 @@
+package proxmox
+
+// Methods for API endpoints that aren't implemented by hand can be generated into api_generated.go from the
+// Proxmox VE API schema. Download https://pve.proxmox.com/pve-docs/api-viewer/apidoc.js of the targeted release and run:
+//
+//	PVE_APIDOC=/path/to/apidoc.js go generate
+//
+// The schema is read from PVE_APIDOC, and nothing is generated if it isn't set.
+// Hand-written methods take precedence, so implementing an endpoint by hand and regenerating removes its generated method.
+
+//go:generate go run ./internal/apigen -out api_generated.go
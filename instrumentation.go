@@ -0,0 +1,85 @@
+package proxmox
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Operation describes the API call a request is made for
+type Operation struct {
+	// Name identifies the API call. For requests made by a service method it's the service and method name,
+	// like "NodeService.GetNodeQemu". Otherwise it's the HTTP method and path template, like "GET nodes/{node}/qemu".
+	Name string
+
+	// Method is the HTTP method of the request
+	Method string
+
+	// Path is the API path of the request relative to the base URL, like "nodes/srv1/qemu"
+	Path string
+
+	// Node is the node name from the API path, if any
+	Node string
+
+	// VMID is the guest ID from the API path, if any
+	VMID string
+}
+
+// Instrumenter is notified around every API call made with Client.Do, for example to create tracing spans or record metrics.
+// StartOperation is called before the call is made, and may return a derived context which is used for the request.
+// The returned function is called with the final response or error once the call finished.
+type Instrumenter interface {
+	StartOperation(ctx context.Context, op Operation) (context.Context, func(resp *http.Response, err error))
+}
+
+// WithInstrumenter sets an Instrumenter that is notified around every API call.
+// See the otelproxmox module for an OpenTelemetry implementation.
+func WithInstrumenter(i Instrumenter) ClientOptionFunc {
+	return func(c *Client) error {
+		c.instrumenter = i
+		return nil
+	}
+}
+
+// operationKey is the context key for the name of the service method making a request
+type operationKey struct{}
+
+// withOperation stores the name of the service method making a request in the context
+func withOperation(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, operationKey{}, name)
+}
+
+// operation describes the API call a request is made for
+func (c *Client) operation(req *http.Request) Operation {
+	op := Operation{
+		Method: req.Method,
+		Path:   strings.TrimPrefix(req.URL.Path, c.baseURL.Path),
+	}
+
+	template, node, vmid := pathTemplate(op.Path)
+	op.Node = node
+	op.VMID = vmid
+
+	if name, ok := req.Context().Value(operationKey{}).(string); ok {
+		op.Name = name
+	} else {
+		op.Name = req.Method + " " + template
+	}
+
+	return op
+}
+
+// pathTemplate finds the node and guest ID in API paths like nodes/{node}/qemu/{vmid}/...
+// and returns the path with them replaced by placeholders
+func pathTemplate(path string) (template, node, vmid string) {
+	segments := strings.Split(path, "/")
+	if len(segments) > 1 && segments[0] == "nodes" {
+		node = segments[1]
+		segments[1] = "{node}"
+		if len(segments) > 3 && (segments[2] == "qemu" || segments[2] == "lxc") {
+			vmid = segments[3]
+			segments[3] = "{vmid}"
+		}
+	}
+	return strings.Join(segments, "/"), node, vmid
+}
@@ -0,0 +1,69 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testInstrumenter struct {
+	ops      []Operation
+	statuses []int
+	errs     []error
+}
+
+type testInstrumenterKey struct{}
+
+func (i *testInstrumenter) StartOperation(ctx context.Context, op Operation) (context.Context, func(*http.Response, error)) {
+	i.ops = append(i.ops, op)
+	return context.WithValue(ctx, testInstrumenterKey{}, op.Name), func(resp *http.Response, err error) {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		i.statuses = append(i.statuses, status)
+		i.errs = append(i.errs, err)
+	}
+}
+
+func TestWithInstrumenter(t *testing.T) {
+	inst := &testInstrumenter{}
+	mux, server, client := setup(t,
+		WithInstrumenter(inst),
+		// The context returned by the instrumenter is used for the request
+		WithMiddleware(RequestHook(func(req *http.Request) error {
+			require.NotNil(t, req.Context().Value(testInstrumenterKey{}))
+			return nil
+		})),
+	)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/100/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, fixture("nodes/get_qemu_snapshots.json"))
+		if err != nil {
+			return
+		}
+	})
+	mux.HandleFunc("/api2/json/nodes/srv1/lxc/101/status/current", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	_, _, err := client.Nodes.GetQemuSnapshots("srv1", 100)
+	require.NoError(t, err)
+
+	req, err := client.NewRequest(http.MethodGet, "nodes/srv1/lxc/101/status/current", nil)
+	require.NoError(t, err)
+	_, err = client.Do(req, nil)
+	require.Error(t, err)
+
+	require.Equal(t, []Operation{
+		{Name: "NodeService.GetQemuSnapshots", Method: http.MethodGet, Path: "nodes/srv1/qemu/100/snapshot", Node: "srv1", VMID: "100"},
+		{Name: "GET nodes/{node}/lxc/{vmid}/status/current", Method: http.MethodGet, Path: "nodes/srv1/lxc/101/status/current", Node: "srv1", VMID: "101"},
+	}, inst.ops)
+	require.Equal(t, []int{http.StatusOK, http.StatusInternalServerError}, inst.statuses)
+	require.NoError(t, inst.errs[0])
+	require.Error(t, inst.errs[1])
+}
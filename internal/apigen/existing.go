@@ -0,0 +1,105 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// endpointComment matches the first line of the doc comment of hand-written API methods
+var endpointComment = regexp.MustCompile(`makes an? (GET|POST|PUT|DELETE) request to the (/\S+) endpoint`)
+
+// declarations are the names and endpoints already declared in the package the code is generated for
+type declarations struct {
+	// names are the package level identifiers
+	names map[string]bool
+
+	// endpoints are the endpoints of hand-written methods, e.g. "GET /nodes/{node}/status"
+	endpoints map[string]bool
+}
+
+// scanPackage collects the declarations of the non-test Go files in dir, except the file code is generated to
+func scanPackage(dir, generated string) (*declarations, error) {
+	d := &declarations{names: map[string]bool{}, endpoints: map[string]bool{}}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || sameFile(file, generated) {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		d.add(f)
+	}
+
+	return d, nil
+}
+
+// add collects the declarations of a file
+func (d *declarations) add(f *ast.File) {
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			name := decl.Name.Name
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				name = receiverName(decl.Recv.List[0].Type) + "." + name
+			}
+			d.names[name] = true
+
+			if decl.Doc != nil {
+				if m := endpointComment.FindStringSubmatch(decl.Doc.Text()); m != nil {
+					d.endpoints[m[1]+" "+m[2]] = true
+				}
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					d.names[spec.Name.Name] = true
+				case *ast.ValueSpec:
+					for _, n := range spec.Names {
+						d.names[n.Name] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+// receiverName returns the type name of a method receiver
+func receiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	case *ast.IndexExpr:
+		return receiverName(e.X)
+	default:
+		return ""
+	}
+}
+
+// sameFile reports whether two paths refer to the same file
+func sameFile(a, b string) bool {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(ai, bi)
+}
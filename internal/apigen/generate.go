@@ -0,0 +1,525 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// service is a client service that methods of an API path root are generated on
+type service struct {
+	// Name is the type name of the service
+	Name string
+
+	// Noun starts the names of methods below the root, e.g. GetNodeStatus
+	Noun string
+
+	// Plural names methods of the root itself, e.g. GetNodes
+	Plural string
+}
+
+// services are the client services by API path root. Paths of other roots are skipped.
+var services = map[string]service{
+	"cluster": {Name: "ClusterService", Noun: "Cluster", Plural: "Cluster"},
+	"nodes":   {Name: "NodeService", Noun: "Node", Plural: "Nodes"},
+}
+
+// verbs start method names by HTTP method
+var verbs = map[string]string{
+	"GET":    "Get",
+	"POST":   "Create",
+	"PUT":    "Update",
+	"DELETE": "Delete",
+}
+
+// httpMethods are the net/http constants by HTTP method
+var httpMethods = map[string]string{
+	"GET":    "http.MethodGet",
+	"POST":   "http.MethodPost",
+	"PUT":    "http.MethodPut",
+	"DELETE": "http.MethodDelete",
+}
+
+// initialisms are name parts written in upper case, following the names used in the hand-written code
+var initialisms = map[string]string{
+	"acl": "ACL", "acme": "ACME", "api": "API", "cpu": "CPU", "cpus": "CPUs", "dns": "DNS", "ha": "HA", "http": "HTTP",
+	"id": "ID", "ip": "IP", "json": "JSON", "mac": "MAC", "sdn": "SDN", "ssl": "SSL", "tls": "TLS", "upid": "UPID",
+	"url": "URL", "uuid": "UUID", "vm": "VM", "vmid": "VMID",
+}
+
+// reservedArgs are names used by the generated methods, path parameters with these names are renamed
+var reservedArgs = map[string]bool{
+	"context": true, "ctx": true, "d": true, "err": true, "fmt": true, "http": true, "opt": true, "req": true, "resp": true,
+	"s": true, "u": true,
+}
+
+// generator generates the Go code of API methods
+type generator struct {
+	decls   *declarations
+	include []string
+
+	buf     bytes.Buffer
+	usesFmt bool
+
+	// names are the identifiers generated so far, by the endpoint they were generated for
+	names map[string]string
+
+	// skipped explains why endpoints were skipped
+	skipped []string
+}
+
+// pathParam is a parameter in an API path, e.g. {vmid}
+type pathParam struct {
+	name string
+	arg  string
+	typ  string
+}
+
+// generate returns the Go code of the API methods for the endpoints of the given services,
+// and the reasons endpoints were skipped. Endpoints with hand-written methods are skipped.
+// If include isn't empty, only paths starting with one of its prefixes are generated.
+func generate(eps []endpoint, decls *declarations, include []string) ([]byte, []string, error) {
+	g := &generator{decls: decls, include: include, names: map[string]string{}}
+
+	var body bytes.Buffer
+	for _, ep := range eps {
+		g.buf.Reset()
+		if g.endpoint(ep) {
+			body.Write(g.buf.Bytes())
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by apigen from the Proxmox VE API schema. DO NOT EDIT.\n\n")
+	out.WriteString("package proxmox\n\n")
+	if body.Len() > 0 {
+		out.WriteString("import (\n\t\"context\"\n")
+		if g.usesFmt {
+			out.WriteString("\t\"fmt\"\n")
+		}
+		out.WriteString("\t\"net/http\"\n)\n")
+	}
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, nil, fmt.Errorf("error formatting generated code: %w\n%s", err, out.Bytes())
+	}
+
+	return src, g.skipped, nil
+}
+
+// included reports whether a path should be generated
+func (g *generator) included(path string) bool {
+	if len(g.include) == 0 {
+		return true
+	}
+	for _, prefix := range g.include {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// endpoint writes the code for an endpoint, and reports whether it did
+func (g *generator) endpoint(ep endpoint) bool {
+	key := ep.Method + " " + ep.Path
+	if !g.included(ep.Path) {
+		return false
+	}
+	if g.decls.endpoints[key] {
+		g.skipped = append(g.skipped, key+": already implemented")
+		return false
+	}
+
+	segments := strings.Split(strings.Trim(ep.Path, "/"), "/")
+	svc, ok := services[segments[0]]
+	if !ok {
+		g.skipped = append(g.skipped, key+": no service for /"+segments[0])
+		return false
+	}
+
+	name := methodName(svc, ep.Method, segments)
+	for _, n := range []string{svc.Name + "." + name, svc.Name + "." + name + "WithContext", name + "Options", name + "Response", name + "Data"} {
+		if g.decls.names[n] {
+			g.skipped = append(g.skipped, fmt.Sprintf("%s: %s is already declared", key, n))
+			return false
+		}
+		if other, ok := g.names[n]; ok {
+			g.skipped = append(g.skipped, fmt.Sprintf("%s: %s was already generated for %s", key, n, other))
+			return false
+		}
+	}
+	for _, n := range []string{svc.Name + "." + name, svc.Name + "." + name + "WithContext", name + "Options", name + "Response", name + "Data"} {
+		g.names[n] = key
+	}
+
+	params := pathParams(ep, segments)
+	hasOptions := g.options(name, ep, params)
+	respType := g.response(name, ep)
+	g.methods(svc, name, ep, params, hasOptions, respType)
+
+	return true
+}
+
+// methodName returns the name of the method for an endpoint, e.g. GetNodeQemuStatusCurrent.
+// Paths ending with a parameter are suffixed with it, e.g. DeleteClusterHAGroupsByGroup.
+func methodName(svc service, method string, segments []string) string {
+	var sb strings.Builder
+	sb.WriteString(verbs[method])
+
+	rest := segments[1:]
+	if len(rest) == 0 {
+		sb.WriteString(svc.Plural)
+		return sb.String()
+	}
+
+	sb.WriteString(svc.Noun)
+	for _, seg := range rest {
+		if !isParam(seg) {
+			sb.WriteString(goName(seg))
+		}
+	}
+	if last := rest[len(rest)-1]; len(rest) > 1 && isParam(last) {
+		sb.WriteString("By" + goName(strings.Trim(last, "{}")))
+	}
+
+	return sb.String()
+}
+
+// pathParams returns the parameters in the path of an endpoint
+func pathParams(ep endpoint, segments []string) []pathParam {
+	var params []pathParam
+	for _, seg := range segments {
+		if !isParam(seg) {
+			continue
+		}
+		name := strings.Trim(seg, "{}")
+
+		p := pathParam{name: name, arg: argName(name), typ: "string"}
+		if t := ep.param(name); t != nil && t.Type == "integer" {
+			p.typ = "int"
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+// param returns the schema of a parameter of the endpoint
+func (ep endpoint) param(name string) *schemaType {
+	if ep.Parameters == nil {
+		return nil
+	}
+	return ep.Parameters.Properties[name]
+}
+
+// options writes the options struct of an endpoint, and reports whether it has one
+func (g *generator) options(name string, ep endpoint, params []pathParam) bool {
+	if ep.Parameters == nil {
+		return false
+	}
+
+	isPathParam := map[string]bool{}
+	for _, p := range params {
+		isPathParam[p.name] = true
+	}
+
+	var fields []string
+	seen := map[string]bool{}
+	var indexed []string
+	for _, pname := range sortedKeys(ep.Parameters.Properties) {
+		if isPathParam[pname] {
+			continue
+		}
+		// Indexed parameters like net[n] can't be expressed with url tags
+		if strings.HasSuffix(pname, "[n]") {
+			indexed = append(indexed, pname)
+			continue
+		}
+
+		fname := goName(pname)
+		if seen[fname] {
+			continue
+		}
+		seen[fname] = true
+
+		p := ep.Parameters.Properties[pname]
+		typ, tag := optionType(p)
+		if p.Optional {
+			if !strings.HasPrefix(typ, "[]") {
+				typ = "*" + typ
+			}
+			tag = pname + ",omitempty" + tag
+		} else {
+			tag = pname + tag
+		}
+		fields = append(fields, fmt.Sprintf("\t%s %s `url:\"%s\"`%s\n", fname, typ, tag, fieldComment(p.Description)))
+	}
+
+	if len(fields) == 0 {
+		return false
+	}
+
+	fmt.Fprintf(&g.buf, "\n// %sOptions contains the parameters for %s\n", name, name)
+	if len(indexed) > 0 {
+		fmt.Fprintf(&g.buf, "// Indexed parameters aren't included: %s\n", strings.Join(indexed, ", "))
+	}
+	fmt.Fprintf(&g.buf, "type %sOptions struct {\n", name)
+	for _, f := range fields {
+		g.buf.WriteString(f)
+	}
+	g.buf.WriteString("}\n")
+
+	return true
+}
+
+// response writes the response types of an endpoint, and returns the name of its response type or an empty string if it returns none
+func (g *generator) response(name string, ep endpoint) string {
+	t := ep.Returns
+	if t == nil || t.Type == "null" || (t.Type == "" && len(t.Properties) == 0) {
+		return ""
+	}
+	// Endpoints changing something that return a plain string start a task and return its UPID,
+	// they use the TaskResponse of the hand-written methods so the task can be waited for
+	if ep.Method != "GET" && t.Type == "string" {
+		return "TaskResponse"
+	}
+
+	var dataType string
+	var props map[string]*schemaType
+	switch {
+	case t.Type == "array" && t.Items != nil && len(t.Items.Properties) > 0:
+		dataType = "[]" + name + "Data"
+		props = t.Items.Properties
+	case (t.Type == "object" || t.Type == "") && len(t.Properties) > 0:
+		dataType = name + "Data"
+		props = t.Properties
+	default:
+		dataType = valueType(t)
+	}
+
+	fmt.Fprintf(&g.buf, "\n// %sResponse contains the response for the %s endpoint\n", name, ep.Path)
+	fmt.Fprintf(&g.buf, "type %sResponse struct {\n\tData %s `json:\"data\"`\n}\n", name, dataType)
+
+	if props != nil {
+		fmt.Fprintf(&g.buf, "\n// %sData contains data from a %s response\n", name, name)
+		fmt.Fprintf(&g.buf, "type %sData struct {\n", name)
+		seen := map[string]bool{}
+		for _, pname := range sortedKeys(props) {
+			fname := goName(pname)
+			if seen[fname] {
+				continue
+			}
+			seen[fname] = true
+
+			p := props[pname]
+			typ := valueType(p)
+			if pname == "vmid" {
+				typ = "IntOrString"
+			}
+			// Optional fields are pointers, so missing fields can be told apart from zero values
+			if bool(p.Optional) && !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && typ != "interface{}" {
+				typ = "*" + typ
+			}
+			fmt.Fprintf(&g.buf, "\t%s %s `json:\"%s\"`%s\n", fname, typ, pname, fieldComment(p.Description))
+		}
+		g.buf.WriteString("}\n")
+	}
+
+	return name + "Response"
+}
+
+// methods writes the method of an endpoint and its WithContext variant
+func (g *generator) methods(svc service, name string, ep endpoint, params []pathParam, hasOptions bool, respType string) {
+	var args, argNames []string
+	for _, p := range params {
+		args = append(args, p.arg+" "+p.typ)
+		argNames = append(argNames, p.arg)
+	}
+	if hasOptions {
+		args = append(args, "opt *"+name+"Options")
+		argNames = append(argNames, "opt")
+	}
+
+	results := "(*http.Response, error)"
+	if respType != "" {
+		results = "(*" + respType + ", *http.Response, error)"
+	}
+
+	// Plain method
+	fmt.Fprintf(&g.buf, "\n// %s makes a %s request to the %s endpoint\n", name, ep.Method, ep.Path)
+	if desc := firstSentence(ep.Description); desc != "" {
+		fmt.Fprintf(&g.buf, "// %s\n", desc)
+	}
+	fmt.Fprintf(&g.buf, "// https://pve.proxmox.com/pve-docs/api-viewer/index.html#%s\n", ep.Path)
+	fmt.Fprintf(&g.buf, "func (s *%s) %s(%s) %s {\n", svc.Name, name, strings.Join(args, ", "), results)
+	fmt.Fprintf(&g.buf, "\treturn s.%sWithContext(%s)\n}\n", name, strings.Join(append([]string{"context.Background()"}, argNames...), ", "))
+
+	// WithContext variant
+	fmt.Fprintf(&g.buf, "\n// %sWithContext is like %s but uses the given context for the request\n", name, name)
+	fmt.Fprintf(&g.buf, "func (s *%s) %sWithContext(%s) %s {\n", svc.Name, name, strings.Join(append([]string{"ctx context.Context"}, args...), ", "), results)
+
+	path := strings.TrimPrefix(ep.Path, "/")
+	if len(params) == 0 {
+		fmt.Fprintf(&g.buf, "\tu := %q\n", path)
+	} else {
+		g.usesFmt = true
+		format := path
+		var formatArgs []string
+		for _, p := range params {
+			verb := "%s"
+			if p.typ == "int" {
+				verb = "%d"
+			}
+			format = strings.Replace(format, "{"+p.name+"}", verb, 1)
+			formatArgs = append(formatArgs, p.arg)
+		}
+		fmt.Fprintf(&g.buf, "\tu := fmt.Sprintf(%q, %s)\n", format, strings.Join(formatArgs, ", "))
+	}
+
+	opt := "nil"
+	if hasOptions {
+		opt = "opt"
+	}
+	fmt.Fprintf(&g.buf, "\treq, err := s.client.NewRequestWithContext(withOperation(ctx, %q), %s, u, %s)\n", svc.Name+"."+name, httpMethods[ep.Method], opt)
+
+	if respType == "" {
+		g.buf.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n\n")
+		g.buf.WriteString("\treturn s.client.Do(req, nil)\n}\n")
+		return
+	}
+	g.buf.WriteString("\tif err != nil {\n\t\treturn nil, nil, err\n\t}\n\n")
+	fmt.Fprintf(&g.buf, "\td := new(%s)\n", respType)
+	g.buf.WriteString("\tresp, err := s.client.Do(req, d)\n\tif err != nil {\n\t\treturn nil, resp, err\n\t}\n\n")
+	g.buf.WriteString("\treturn d, resp, nil\n}\n")
+}
+
+// optionType returns the Go type and extra url tag options of a request parameter
+func optionType(t *schemaType) (string, string) {
+	switch t.Type {
+	case "integer":
+		return "int", ""
+	case "number":
+		return "float64", ""
+	case "boolean":
+		// Proxmox expects booleans as 0 or 1
+		return "bool", ",int"
+	case "array":
+		return "[]string", ""
+	default:
+		return "string", ""
+	}
+}
+
+// valueType returns the Go type of a value in a response
+func valueType(t *schemaType) string {
+	switch t.Type {
+	case "string":
+		return "string"
+	case "integer":
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		// Proxmox returns booleans as 0 or 1
+		return "int"
+	case "array":
+		if t.Items != nil {
+			switch item := valueType(t.Items); item {
+			case "string", "int", "float64":
+				return "[]" + item
+			}
+			if t.Items.Type == "object" {
+				return "[]map[string]interface{}"
+			}
+		}
+		return "[]interface{}"
+	case "object":
+		return "map[string]interface{}"
+	default:
+		return "interface{}"
+	}
+}
+
+// isParam reports whether a path segment is a parameter, e.g. {node}
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// goName returns the exported Go name of an API name, e.g. "overrule-shutdown" becomes "OverruleShutdown"
+func goName(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var sb strings.Builder
+	for _, p := range parts {
+		if upper, ok := initialisms[strings.ToLower(p)]; ok {
+			sb.WriteString(upper)
+			continue
+		}
+		sb.WriteString(strings.ToUpper(p[:1]) + p[1:])
+	}
+
+	name := sb.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "P" + name
+	}
+	return name
+}
+
+// argName returns the unexported Go name of a path parameter, e.g. "vmid" stays "vmid"
+func argName(s string) string {
+	name := goName(s)
+	for i, r := range name {
+		if !unicode.IsUpper(r) {
+			// Lower the leading initialism, keeping the start of the next word upper case
+			if i > 1 {
+				i--
+			}
+			name = strings.ToLower(name[:i]) + name[i:]
+			break
+		}
+		if i == len(name)-1 {
+			name = strings.ToLower(name)
+		}
+	}
+
+	if token.IsKeyword(name) || reservedArgs[name] {
+		name += "Param"
+	}
+	return name
+}
+
+// firstSentence returns the first sentence of a description on one line
+func firstSentence(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if idx := strings.Index(s, ". "); idx >= 0 {
+		s = s[:idx+1]
+	}
+	return s
+}
+
+// fieldComment returns a trailing comment with the first sentence of a description, without its period
+func fieldComment(description string) string {
+	desc := strings.TrimSuffix(firstSentence(description), ".")
+	if desc == "" {
+		return ""
+	}
+	return " // " + desc
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys(m map[string]*schemaType) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
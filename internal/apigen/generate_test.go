@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"overrule-shutdown": "OverruleShutdown",
+		"vmid":              "VMID",
+		"cpus":              "CPUs",
+		"ha":                "HA",
+		"storage_id":        "StorageID",
+		"9p":                "P9p",
+		"snapname":          "Snapname",
+	}
+	for in, want := range tests {
+		require.Equal(t, want, goName(in), in)
+	}
+}
+
+func TestArgName(t *testing.T) {
+	tests := map[string]string{
+		"node":     "node",
+		"vmid":     "vmid",
+		"upid":     "upid",
+		"ha-group": "haGroup",
+		"type":     "typeParam",
+		"u":        "uParam",
+	}
+	for in, want := range tests {
+		require.Equal(t, want, argName(in), in)
+	}
+}
+
+func TestMethodName(t *testing.T) {
+	nodes := services["nodes"]
+	require.Equal(t, "GetNodes", methodName(nodes, "GET", []string{"nodes"}))
+	require.Equal(t, "GetNode", methodName(nodes, "GET", []string{"nodes", "{node}"}))
+	require.Equal(t, "CreateNodeQemuStatusStart", methodName(nodes, "POST", []string{"nodes", "{node}", "qemu", "{vmid}", "status", "start"}))
+	require.Equal(t, "DeleteNodeQemuByVMID", methodName(nodes, "DELETE", []string{"nodes", "{node}", "qemu", "{vmid}"}))
+	require.Equal(t, "UpdateClusterHAGroupsByGroup", methodName(services["cluster"], "PUT", []string{"cluster", "ha", "groups", "{group}"}))
+}
+
+func TestResponseType(t *testing.T) {
+	g := &generator{}
+	str := &schemaMethod{Returns: &schemaType{Type: "string"}}
+	require.Equal(t, "TaskResponse", g.response("CreateNodeQemuStatusStart", endpoint{Path: "/nodes/{node}/qemu/{vmid}/status/start", Method: "POST", schemaMethod: str}))
+	require.Equal(t, "TaskResponse", g.response("DeleteNodeQemuByVMID", endpoint{Path: "/nodes/{node}/qemu/{vmid}", Method: "DELETE", schemaMethod: str}))
+	require.Empty(t, g.buf.String())
+
+	require.Equal(t, "GetNodeVersionResponse", g.response("GetNodeVersion", endpoint{Path: "/nodes/{node}/version", Method: "GET", schemaMethod: str}))
+	require.Contains(t, g.buf.String(), "type GetNodeVersionResponse struct {\n\tData string `json:\"data\"`\n}")
+	require.Empty(t, g.response("CreateClusterHAGroups", endpoint{Path: "/cluster/ha/groups", Method: "POST", schemaMethod: &schemaMethod{Returns: &schemaType{Type: "null"}}}))
+}
@@ -0,0 +1,73 @@
+// Command apigen generates API methods of the proxmox package from the Proxmox VE API schema, apidoc.json.
+// It emits request option structs, response types and service methods in the same style as the hand-written methods,
+// and skips endpoints whose methods were written by hand, so it can be run again for every Proxmox VE release.
+//
+// Download the schema from https://pve.proxmox.com/pve-docs/api-viewer/apidoc.js and run:
+//
+//	PVE_APIDOC=/path/to/apidoc.js go generate
+//
+// in the repository root. Without PVE_APIDOC nothing is generated. See generate.go in the proxmox package for the flags used.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	schema := flag.String("schema", os.Getenv("PVE_APIDOC"), "path of the API schema, apidoc.json or apidoc.js. Default: $PVE_APIDOC")
+	out := flag.String("out", "api_generated.go", "file to write the generated code to")
+	dir := flag.String("dir", ".", "directory of the proxmox package, checked for hand-written methods")
+	include := flag.String("include", "", "comma separated path prefixes to generate, e.g. /nodes/{node}/qemu. Default: all paths")
+	verbose := flag.Bool("v", false, "print skipped endpoints")
+	flag.Parse()
+
+	// go generate runs for the whole package, so running it without a schema isn't an error
+	if *schema == "" {
+		fmt.Fprintln(os.Stderr, "apigen: skipping, no API schema given. Set PVE_APIDOC or -schema to the path of apidoc.js")
+		return
+	}
+
+	if err := run(*schema, *out, *dir, *include, *verbose); err != nil {
+		fmt.Fprintln(os.Stderr, "apigen:", err)
+		os.Exit(1)
+	}
+}
+
+// run generates the code for the schema at schemaPath and writes it to out
+func run(schemaPath, out, dir, include string, verbose bool) error {
+	nodes, err := loadSchema(schemaPath)
+	if err != nil {
+		return err
+	}
+
+	if !filepath.IsAbs(out) {
+		out = filepath.Join(dir, out)
+	}
+	decls, err := scanPackage(dir, out)
+	if err != nil {
+		return err
+	}
+
+	var prefixes []string
+	for _, p := range strings.Split(include, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			prefixes = append(prefixes, "/"+strings.TrimPrefix(p, "/"))
+		}
+	}
+
+	src, skipped, err := generate(endpoints(nodes), decls, prefixes)
+	if err != nil {
+		return err
+	}
+	if verbose {
+		for _, s := range skipped {
+			fmt.Fprintln(os.Stderr, "skipped", s)
+		}
+	}
+
+	return os.WriteFile(out, src, 0o644)
+}
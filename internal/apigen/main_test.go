@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestRun(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "api_generated.go")
+	require.NoError(t, run("testdata/apidoc.json", out, "testdata/pkg", "", false))
+
+	got, err := os.ReadFile(out)
+	require.NoError(t, err)
+
+	golden := "testdata/api_generated.golden"
+	if *update {
+		require.NoError(t, os.WriteFile(golden, got, 0o644))
+	}
+	want, err := os.ReadFile(golden)
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(got))
+}
+
+func TestRunInclude(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "api_generated.go")
+	require.NoError(t, run("testdata/apidoc.json", out, "testdata/pkg", "nodes/{node}/qemu", false))
+
+	got, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Contains(t, string(got), "func (s *NodeService) CreateNodeQemuStatusStart(")
+	require.NotContains(t, string(got), "ClusterService")
+}
+
+func TestRunNoSchema(t *testing.T) {
+	require.Error(t, run("", "api_generated.go", "testdata/pkg", "", false))
+}
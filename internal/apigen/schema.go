@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// schemaNode is a path of the API schema, as published in apidoc.json
+type schemaNode struct {
+	Path     string                   `json:"path"`
+	Info     map[string]*schemaMethod `json:"info"`
+	Children []*schemaNode            `json:"children"`
+}
+
+// schemaMethod is an HTTP method of a path in the API schema
+type schemaMethod struct {
+	Description string      `json:"description"`
+	Parameters  *schemaType `json:"parameters"`
+	Returns     *schemaType `json:"returns"`
+}
+
+// schemaType is a JSON schema of a parameter or return value
+type schemaType struct {
+	Type        schemaTypeName         `json:"type"`
+	Description string                 `json:"description"`
+	Optional    schemaBool             `json:"optional"`
+	Properties  map[string]*schemaType `json:"properties"`
+	Items       *schemaType            `json:"items"`
+}
+
+// schemaTypeName is the type of a schema, which the schema sometimes gives as a list of types
+type schemaTypeName string
+
+// UnmarshalJSON implements the json.Unmarshaler interface for schemaTypeName, using the first type of a list that isn't null
+func (t *schemaTypeName) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*t = schemaTypeName(name)
+		return nil
+	}
+
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return fmt.Errorf("invalid schema type %s", b)
+	}
+	for _, n := range names {
+		if n != "null" {
+			*t = schemaTypeName(n)
+			return nil
+		}
+	}
+	*t = "null"
+	return nil
+}
+
+// schemaBool is a schema boolean, which the schema gives as 0 or 1 and sometimes as true or false
+type schemaBool bool
+
+// UnmarshalJSON implements the json.Unmarshaler interface for schemaBool
+func (f *schemaBool) UnmarshalJSON(b []byte) error {
+	switch strings.Trim(string(b), `"`) {
+	case "1", "true":
+		*f = true
+	case "0", "false", "", "null":
+		*f = false
+	default:
+		return fmt.Errorf("invalid schema boolean %s", b)
+	}
+	return nil
+}
+
+// endpoint is an HTTP method of an API path
+type endpoint struct {
+	// Path is the API path without the /api2/json prefix, e.g. /nodes/{node}/qemu
+	Path string
+
+	// Method is the HTTP method, e.g. GET
+	Method string
+
+	*schemaMethod
+}
+
+// methodOrder sorts the endpoints of a path
+var methodOrder = map[string]int{"GET": 0, "POST": 1, "PUT": 2, "DELETE": 3}
+
+// loadSchema reads the API schema from apidoc.json, or from the apidoc.js file the API viewer loads it from
+func loadSchema(path string) ([]*schemaNode, error) {
+	if path == "" {
+		return nil, errors.New("no schema file given, download https://pve.proxmox.com/pve-docs/api-viewer/apidoc.js first")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// apidoc.js assigns the schema to a variable, followed by more code
+	if !bytes.HasPrefix(bytes.TrimSpace(b), []byte("[")) {
+		idx := bytes.Index(b, []byte("apiSchema"))
+		if idx < 0 {
+			return nil, fmt.Errorf("no API schema found in %s", path)
+		}
+		start := bytes.IndexByte(b[idx:], '[')
+		if start < 0 {
+			return nil, fmt.Errorf("no API schema found in %s", path)
+		}
+		b = b[idx+start:]
+	}
+
+	var nodes []*schemaNode
+	if err := json.NewDecoder(bytes.NewReader(b)).Decode(&nodes); err != nil {
+		return nil, fmt.Errorf("error parsing API schema %s: %w", path, err)
+	}
+
+	return nodes, nil
+}
+
+// endpoints returns every endpoint of the schema, sorted by path and method
+func endpoints(nodes []*schemaNode) []endpoint {
+	var eps []endpoint
+
+	var walk func(nodes []*schemaNode)
+	walk = func(nodes []*schemaNode) {
+		for _, n := range nodes {
+			for method, info := range n.Info {
+				if _, ok := methodOrder[method]; ok && info != nil {
+					eps = append(eps, endpoint{Path: n.Path, Method: method, schemaMethod: info})
+				}
+			}
+			walk(n.Children)
+		}
+	}
+	walk(nodes)
+
+	sort.Slice(eps, func(i, j int) bool {
+		if eps[i].Path != eps[j].Path {
+			return eps[i].Path < eps[j].Path
+		}
+		return methodOrder[eps[i].Method] < methodOrder[eps[j].Method]
+	})
+
+	return eps
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSchema(t *testing.T) {
+	nodes, err := loadSchema("testdata/apidoc.json")
+	require.NoError(t, err)
+
+	eps := endpoints(nodes)
+	var keys []string
+	for _, ep := range eps {
+		keys = append(keys, ep.Method+" "+ep.Path)
+	}
+	require.Equal(t, []string{
+		"GET /cluster",
+		"GET /cluster/ha/groups",
+		"POST /cluster/ha/groups",
+		"DELETE /cluster/ha/groups/{group}",
+		"GET /cluster/status",
+		"GET /nodes",
+		"GET /nodes/{node}/qemu/{vmid}/status/current",
+		"POST /nodes/{node}/qemu/{vmid}/status/start",
+		"GET /nodes/{node}/status",
+		"GET /nodes/{node}/tasks/{upid}/log",
+		"GET /version",
+	}, keys)
+
+	// The API viewer's apidoc.js wraps the schema in JavaScript
+	b, err := os.ReadFile("testdata/apidoc.json")
+	require.NoError(t, err)
+	js := filepath.Join(t.TempDir(), "apidoc.js")
+	require.NoError(t, os.WriteFile(js, []byte("const apiSchema = "+string(b)+";\nlet method2cmd = {};\n"), 0o644))
+
+	jsNodes, err := loadSchema(js)
+	require.NoError(t, err)
+	require.Equal(t, len(eps), len(endpoints(jsNodes)))
+
+	_, err = loadSchema("testdata/missing.json")
+	require.Error(t, err)
+}
+
+func TestScanPackage(t *testing.T) {
+	decls, err := scanPackage("testdata/pkg", "")
+	require.NoError(t, err)
+	require.True(t, decls.endpoints["GET /nodes/{node}/status"])
+	require.True(t, decls.names["NodeService.GetNodeStatus"])
+	require.True(t, decls.names["GetClusterHAGroupsResponse"])
+	require.False(t, decls.endpoints["GET /cluster/status"])
+}
@@ -0,0 +1,13 @@
+package proxmox
+
+// GetNodes makes a GET request to the /nodes endpoint
+func (s *NodeService) GetNodes() {}
+
+// GetNodeStatus makes a GET request to the /nodes/{node}/status endpoint
+func (s *NodeService) GetNodeStatus(name string) {}
+
+// GetTaskLog makes a GET request to the /nodes/{node}/tasks/{upid}/log endpoint
+func (s *TaskService) GetTaskLog(upid string) {}
+
+// GetClusterHAGroupsResponse is declared by hand
+type GetClusterHAGroupsResponse struct{}
@@ -0,0 +1,119 @@
+package proxmox
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// redacted replaces the values of headers carrying credentials in logs
+const redacted = "REDACTED"
+
+// redactedHeaders are the headers whose values are never logged, since they carry API tokens, tickets or CSRF tokens
+var redactedHeaders = map[string]bool{
+	"Authorization":                         true,
+	"Proxy-Authorization":                   true,
+	"Cookie":                                true,
+	"Set-Cookie":                            true,
+	http.CanonicalHeaderKey(csrfHeaderName): true,
+}
+
+// WithLogger sets a logger the client logs every API request to at debug level, with its method, path, status,
+// duration and number of attempts, as well as every retry. Headers carrying credentials, like the Authorization header
+// and ticket cookies, are always redacted. Request and response bodies are never logged.
+// Default: nothing is logged
+func WithLogger(logger *slog.Logger) ClientOptionFunc {
+	return func(c *Client) error {
+		c.logger = logger
+		return nil
+	}
+}
+
+// logEnabled reports whether the client logs debug messages
+func (c *Client) logEnabled(ctx context.Context) bool {
+	return c.logger != nil && c.logger.Enabled(ctx, slog.LevelDebug)
+}
+
+// logRequest logs a finished API request
+func (c *Client) logRequest(req *http.Request, resp *http.Response, err error, attempts int, duration time.Duration) {
+	ctx := req.Context()
+	if !c.logEnabled(ctx) {
+		return
+	}
+
+	attrs := c.requestAttrs(req)
+	attrs = append(attrs, responseAttrs(resp, err)...)
+	attrs = append(attrs,
+		slog.Duration("duration", duration),
+		slog.Int("attempts", attempts),
+		slog.Any("request_headers", headersValue(req.Header)),
+	)
+	if resp != nil {
+		attrs = append(attrs, slog.Any("response_headers", headersValue(resp.Header)))
+	}
+
+	c.logger.LogAttrs(ctx, slog.LevelDebug, "Proxmox API request", attrs...)
+}
+
+// logRetry logs a failed attempt of an API request that is retried after wait
+func (c *Client) logRetry(req *http.Request, resp *http.Response, err error, attempt int, wait time.Duration) {
+	ctx := req.Context()
+	if !c.logEnabled(ctx) {
+		return
+	}
+
+	attrs := c.requestAttrs(req)
+	attrs = append(attrs, responseAttrs(resp, err)...)
+	attrs = append(attrs,
+		slog.Int("attempt", attempt),
+		slog.Duration("wait", wait),
+	)
+
+	c.logger.LogAttrs(ctx, slog.LevelDebug, "Retrying Proxmox API request", attrs...)
+}
+
+// requestAttrs are the log attributes describing a request
+func (c *Client) requestAttrs(req *http.Request) []slog.Attr {
+	op := c.operation(req)
+	return []slog.Attr{
+		slog.String("operation", op.Name),
+		slog.String("method", op.Method),
+		slog.String("path", op.Path),
+		slog.String("host", req.URL.Host),
+	}
+}
+
+// responseAttrs are the log attributes describing the response or error of a request
+func responseAttrs(resp *http.Response, err error) []slog.Attr {
+	var attrs []slog.Attr
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	return attrs
+}
+
+// headersValue returns the headers as a log group sorted by name, with the values of credential headers redacted
+func headersValue(h http.Header) slog.Value {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attrs := make([]slog.Attr, 0, len(names))
+	for _, name := range names {
+		value := redacted
+		if !redactedHeaders[http.CanonicalHeaderKey(name)] {
+			value = strings.Join(h[name], ", ")
+		}
+		attrs = append(attrs, slog.String(name, value))
+	}
+
+	return slog.GroupValue(attrs...)
+}
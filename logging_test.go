@@ -0,0 +1,138 @@
+package proxmox
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// logRecords parses the records of a JSON log handler
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+// newDebugLogger returns a logger writing JSON records at debug level to buf
+func newDebugLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+func TestWithLogger(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	var buf bytes.Buffer
+	require.NoError(t, WithLogger(newDebugLogger(&buf))(client))
+
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+	})
+
+	_, _, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+
+	records := logRecords(t, &buf)
+	require.Len(t, records, 1)
+	r := records[0]
+	require.Equal(t, "DEBUG", r["level"])
+	require.Equal(t, "Proxmox API request", r["msg"])
+	require.Equal(t, "NodeService.GetNodes", r["operation"])
+	require.Equal(t, http.MethodGet, r["method"])
+	require.Equal(t, "nodes", r["path"])
+	require.Equal(t, float64(http.StatusOK), r["status"])
+	require.Equal(t, float64(1), r["attempts"])
+	require.Contains(t, r, "duration")
+	require.Equal(t, "REDACTED", r["request_headers"].(map[string]interface{})["Authorization"])
+	require.NotContains(t, buf.String(), "test-token-id")
+}
+
+func TestWithLoggerTicket(t *testing.T) {
+	mux, server, client, _ := setupWithPassword(t)
+	defer teardown(server)
+
+	var buf bytes.Buffer
+	require.NoError(t, WithLogger(newDebugLogger(&buf))(client))
+
+	mux.HandleFunc("/api2/json/nodes/node1/qemu/100/status/start", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"data":null}`)
+	})
+
+	req, err := client.NewRequest(http.MethodPost, "nodes/node1/qemu/100/status/start", nil)
+	require.NoError(t, err)
+	_, err = client.Do(req, nil)
+	require.NoError(t, err)
+
+	// The ticket request and the API request are logged, without the password, ticket or CSRF token
+	records := logRecords(t, &buf)
+	require.Len(t, records, 2)
+	require.Equal(t, "access/ticket", records[0]["path"])
+	require.Equal(t, "nodes/node1/qemu/100/status/start", records[1]["path"])
+	headers := records[1]["request_headers"].(map[string]interface{})
+	require.Equal(t, "REDACTED", headers["Cookie"])
+	require.Equal(t, "REDACTED", headers["Csrfpreventiontoken"])
+	require.NotContains(t, buf.String(), "secret")
+	require.NotContains(t, buf.String(), "ticket1")
+	require.NotContains(t, buf.String(), "csrf1")
+}
+
+func TestWithLoggerRetry(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	var buf bytes.Buffer
+	require.NoError(t, WithLogger(newDebugLogger(&buf))(client))
+	require.NoError(t, WithRetry(RetryPolicy{MinBackoff: time.Millisecond})(client))
+
+	var attempts int32
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(595)
+			return
+		}
+		_, _ = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+	})
+
+	_, _, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+
+	records := logRecords(t, &buf)
+	require.Len(t, records, 2)
+	require.Equal(t, "Retrying Proxmox API request", records[0]["msg"])
+	require.Equal(t, float64(595), records[0]["status"])
+	require.Equal(t, float64(1), records[0]["attempt"])
+	require.Equal(t, "Proxmox API request", records[1]["msg"])
+	require.Equal(t, float64(2), records[1]["attempts"])
+}
+
+func TestWithLoggerLevel(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	// Nothing is logged unless the logger is enabled for debug messages
+	var buf bytes.Buffer
+	require.NoError(t, WithLogger(slog.New(slog.NewJSONHandler(&buf, nil)))(client))
+
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+	})
+
+	_, _, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Empty(t, buf.String())
+}
@@ -0,0 +1,175 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// GetLxcStatusCurrentResponse contains the response for the /nodes/{node}/lxc/{vmid}/status/current endpoint
+type GetLxcStatusCurrentResponse struct {
+	Data GetLxcStatusCurrentData `json:"data"`
+}
+
+// GetLxcStatusCurrentData contains the current status of a container from a GetLxcStatusCurrent response
+type GetLxcStatusCurrentData struct {
+	Status    string      `json:"status"` // Either "running" or "stopped"
+	VMID      IntOrString `json:"vmid"`
+	HA        HAStatus    `json:"ha"`
+	CPU       *float64    `json:"cpu,omitempty"`
+	CPUs      *float64    `json:"cpus,omitempty"` // Can be fractional, like 0.5 with a CPU limit
+	Disk      *int64      `json:"disk,omitempty"`
+	DiskRead  *int64      `json:"diskread,omitempty"`
+	DiskWrite *int64      `json:"diskwrite,omitempty"`
+	Lock      *string     `json:"lock,omitempty"`
+	MaxDisk   *int64      `json:"maxdisk,omitempty"`
+	MaxMem    *int64      `json:"maxmem,omitempty"`
+	MaxSwap   *int64      `json:"maxswap,omitempty"`
+	Mem       *int64      `json:"mem,omitempty"`
+	Swap      *int64      `json:"swap,omitempty"`
+	Name      *string     `json:"name,omitempty"`
+	NetIn     *int64      `json:"netin,omitempty"`
+	NetOut    *int64      `json:"netout,omitempty"`
+	PID       *int        `json:"pid,omitempty"`
+	Tags      *string     `json:"tags,omitempty"`
+	Template  *int        `json:"template,omitempty"`
+	Type      *string     `json:"type,omitempty"`
+	Uptime    *int        `json:"uptime,omitempty"`
+}
+
+// GetLxcStatusCurrent makes a GET request to the /nodes/{node}/lxc/{vmid}/status/current endpoint
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/lxc/{vmid}/status/current
+func (s *NodeService) GetLxcStatusCurrent(nodeName string, vmID int) (*GetLxcStatusCurrentResponse, *http.Response, error) {
+	return s.GetLxcStatusCurrentWithContext(context.Background(), nodeName, vmID)
+}
+
+// GetLxcStatusCurrentWithContext is like GetLxcStatusCurrent but uses the given context for the request
+func (s *NodeService) GetLxcStatusCurrentWithContext(ctx context.Context, nodeName string, vmID int) (*GetLxcStatusCurrentResponse, *http.Response, error) {
+	u := fmt.Sprintf("nodes/%s/lxc/%d/status/current", nodeName, vmID)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.GetLxcStatusCurrent"), http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := new(GetLxcStatusCurrentResponse)
+	resp, err := s.client.Do(req, d)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return d, resp, nil
+}
+
+// StartLxcOptions contains the optional parameters for the /nodes/{node}/lxc/{vmid}/status/start endpoint
+type StartLxcOptions struct {
+	Debug    bool `url:"debug,omitempty,int"`    // Write a debug log of the start to /tmp/lxc-ID.log
+	SkipLock bool `url:"skiplock,omitempty,int"` // Ignore locks, only root is allowed to use this option
+}
+
+// StartLxc makes a POST request to the /nodes/{node}/lxc/{vmid}/status/start endpoint
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/lxc/{vmid}/status/start
+func (s *NodeService) StartLxc(nodeName string, vmID int, opt *StartLxcOptions) (*TaskResponse, *http.Response, error) {
+	return s.StartLxcWithContext(context.Background(), nodeName, vmID, opt)
+}
+
+// StartLxcWithContext is like StartLxc but uses the given context for the request
+func (s *NodeService) StartLxcWithContext(ctx context.Context, nodeName string, vmID int, opt *StartLxcOptions) (*TaskResponse, *http.Response, error) {
+	return s.lxcStatus(withOperation(ctx, "NodeService.StartLxc"), nodeName, vmID, "start", opt)
+}
+
+// StopLxcOptions contains the optional parameters for the /nodes/{node}/lxc/{vmid}/status/stop endpoint
+type StopLxcOptions struct {
+	OverruleShutdown bool `url:"overrule-shutdown,omitempty,int"` // Abort active shutdown tasks of the container first, requires Proxmox VE 8.1
+	SkipLock         bool `url:"skiplock,omitempty,int"`          // Ignore locks, only root is allowed to use this option
+}
+
+// StopLxc makes a POST request to the /nodes/{node}/lxc/{vmid}/status/stop endpoint
+// It stops the container immediately. Use ShutdownLxc to shut it down gracefully.
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/lxc/{vmid}/status/stop
+func (s *NodeService) StopLxc(nodeName string, vmID int, opt *StopLxcOptions) (*TaskResponse, *http.Response, error) {
+	return s.StopLxcWithContext(context.Background(), nodeName, vmID, opt)
+}
+
+// StopLxcWithContext is like StopLxc but uses the given context for the request
+func (s *NodeService) StopLxcWithContext(ctx context.Context, nodeName string, vmID int, opt *StopLxcOptions) (*TaskResponse, *http.Response, error) {
+	if opt != nil && opt.OverruleShutdown {
+		if err := s.client.RequireVersion(ctx, Version{Major: 8, Minor: 1}, "overrule-shutdown"); err != nil {
+			return nil, nil, err
+		}
+	}
+	return s.lxcStatus(withOperation(ctx, "NodeService.StopLxc"), nodeName, vmID, "stop", opt)
+}
+
+// ShutdownLxcOptions contains the optional parameters for the /nodes/{node}/lxc/{vmid}/status/shutdown endpoint
+type ShutdownLxcOptions struct {
+	ForceStop bool `url:"forceStop,omitempty,int"` // Stop the container if it didn't shut down before the timeout
+	Timeout   int  `url:"timeout,omitempty"`       // Wait at most this many seconds, Proxmox defaults to 60
+}
+
+// ShutdownLxc makes a POST request to the /nodes/{node}/lxc/{vmid}/status/shutdown endpoint
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/lxc/{vmid}/status/shutdown
+func (s *NodeService) ShutdownLxc(nodeName string, vmID int, opt *ShutdownLxcOptions) (*TaskResponse, *http.Response, error) {
+	return s.ShutdownLxcWithContext(context.Background(), nodeName, vmID, opt)
+}
+
+// ShutdownLxcWithContext is like ShutdownLxc but uses the given context for the request
+func (s *NodeService) ShutdownLxcWithContext(ctx context.Context, nodeName string, vmID int, opt *ShutdownLxcOptions) (*TaskResponse, *http.Response, error) {
+	return s.lxcStatus(withOperation(ctx, "NodeService.ShutdownLxc"), nodeName, vmID, "shutdown", opt)
+}
+
+// RebootLxcOptions contains the optional parameters for the /nodes/{node}/lxc/{vmid}/status/reboot endpoint
+type RebootLxcOptions struct {
+	Timeout int `url:"timeout,omitempty"` // Wait at most this many seconds for the shutdown
+}
+
+// RebootLxc makes a POST request to the /nodes/{node}/lxc/{vmid}/status/reboot endpoint
+// It shuts down the container and starts it again, applying pending changes.
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/lxc/{vmid}/status/reboot
+func (s *NodeService) RebootLxc(nodeName string, vmID int, opt *RebootLxcOptions) (*TaskResponse, *http.Response, error) {
+	return s.RebootLxcWithContext(context.Background(), nodeName, vmID, opt)
+}
+
+// RebootLxcWithContext is like RebootLxc but uses the given context for the request
+func (s *NodeService) RebootLxcWithContext(ctx context.Context, nodeName string, vmID int, opt *RebootLxcOptions) (*TaskResponse, *http.Response, error) {
+	return s.lxcStatus(withOperation(ctx, "NodeService.RebootLxc"), nodeName, vmID, "reboot", opt)
+}
+
+// SuspendLxc makes a POST request to the /nodes/{node}/lxc/{vmid}/status/suspend endpoint
+// It freezes the processes of the container. Proxmox VE marks container suspension as experimental.
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/lxc/{vmid}/status/suspend
+func (s *NodeService) SuspendLxc(nodeName string, vmID int) (*TaskResponse, *http.Response, error) {
+	return s.SuspendLxcWithContext(context.Background(), nodeName, vmID)
+}
+
+// SuspendLxcWithContext is like SuspendLxc but uses the given context for the request
+func (s *NodeService) SuspendLxcWithContext(ctx context.Context, nodeName string, vmID int) (*TaskResponse, *http.Response, error) {
+	return s.lxcStatus(withOperation(ctx, "NodeService.SuspendLxc"), nodeName, vmID, "suspend", nil)
+}
+
+// ResumeLxc makes a POST request to the /nodes/{node}/lxc/{vmid}/status/resume endpoint
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/lxc/{vmid}/status/resume
+func (s *NodeService) ResumeLxc(nodeName string, vmID int) (*TaskResponse, *http.Response, error) {
+	return s.ResumeLxcWithContext(context.Background(), nodeName, vmID)
+}
+
+// ResumeLxcWithContext is like ResumeLxc but uses the given context for the request
+func (s *NodeService) ResumeLxcWithContext(ctx context.Context, nodeName string, vmID int) (*TaskResponse, *http.Response, error) {
+	return s.lxcStatus(withOperation(ctx, "NodeService.ResumeLxc"), nodeName, vmID, "resume", nil)
+}
+
+// lxcStatus makes a POST request to a /nodes/{node}/lxc/{vmid}/status/{action} endpoint that starts a task
+func (s *NodeService) lxcStatus(ctx context.Context, nodeName string, vmID int, action string, opt interface{}) (*TaskResponse, *http.Response, error) {
+	u := fmt.Sprintf("nodes/%s/lxc/%d/status/%s", nodeName, vmID, action)
+	req, err := s.client.NewRequestWithContext(ctx, http.MethodPost, u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := new(TaskResponse)
+	resp, err := s.client.Do(req, d)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return d, resp, nil
+}
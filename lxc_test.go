@@ -0,0 +1,129 @@
+package proxmox
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetLxcStatusCurrent(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/lxc/200/status/current", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprint(w, fixture("nodes/get_lxc_status_current.json"))
+		if err != nil {
+			return
+		}
+	})
+
+	r, resp, err := client.Nodes.GetLxcStatusCurrent("srv1", 200)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+
+	d := r.Data
+	require.Equal(t, "running", d.Status)
+	require.Equal(t, IntOrString("200"), d.VMID)
+	require.Equal(t, "dns", *d.Name)
+	require.Equal(t, "lxc", *d.Type)
+	require.Equal(t, 0.5, *d.CPUs)
+	require.Equal(t, int64(41357312), *d.Mem)
+	require.Equal(t, int64(0), *d.Swap)
+	require.Equal(t, "backup", *d.Lock)
+	require.Equal(t, HAStatus{Managed: 0}, d.HA)
+	require.Nil(t, d.Template)
+}
+
+func TestLxcPower(t *testing.T) {
+	tests := []struct {
+		action string
+		call   func(c *Client) (*TaskResponse, *http.Response, error)
+		form   url.Values
+	}{
+		{
+			action: "start",
+			call: func(c *Client) (*TaskResponse, *http.Response, error) {
+				return c.Nodes.StartLxc("srv1", 200, &StartLxcOptions{Debug: true})
+			},
+			form: url.Values{"debug": {"1"}},
+		},
+		{
+			action: "stop",
+			call: func(c *Client) (*TaskResponse, *http.Response, error) {
+				return c.Nodes.StopLxc("srv1", 200, &StopLxcOptions{SkipLock: true})
+			},
+			form: url.Values{"skiplock": {"1"}},
+		},
+		{
+			action: "shutdown",
+			call: func(c *Client) (*TaskResponse, *http.Response, error) {
+				return c.Nodes.ShutdownLxc("srv1", 200, &ShutdownLxcOptions{ForceStop: true, Timeout: 30})
+			},
+			form: url.Values{"forceStop": {"1"}, "timeout": {"30"}},
+		},
+		{
+			action: "reboot",
+			call: func(c *Client) (*TaskResponse, *http.Response, error) {
+				return c.Nodes.RebootLxc("srv1", 200, &RebootLxcOptions{Timeout: 10})
+			},
+			form: url.Values{"timeout": {"10"}},
+		},
+		{
+			action: "suspend",
+			call: func(c *Client) (*TaskResponse, *http.Response, error) {
+				return c.Nodes.SuspendLxc("srv1", 200)
+			},
+			form: url.Values{},
+		},
+		{
+			action: "resume",
+			call: func(c *Client) (*TaskResponse, *http.Response, error) {
+				return c.Nodes.ResumeLxc("srv1", 200)
+			},
+			form: url.Values{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			mux, server, client := setup(t)
+			defer teardown(server)
+
+			upid := fmt.Sprintf("UPID:srv1:00001234:00005678:65A1B2C3:vz%s:200:root@pam:", tt.action)
+			var form url.Values
+			mux.HandleFunc("/api2/json/nodes/srv1/lxc/200/status/"+tt.action, func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+				require.NoError(t, r.ParseForm())
+				form = r.PostForm
+				_, _ = fmt.Fprintf(w, `{"data":%q}`, upid)
+			})
+
+			r, _, err := tt.call(client)
+			require.NoError(t, err)
+			require.Equal(t, upid, r.Data)
+			require.Equal(t, tt.form, form)
+		})
+	}
+}
+
+func TestStopLxcOverruleShutdown(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithServerVersion("8.1.3")(client))
+
+	var form url.Values
+	mux.HandleFunc("/api2/json/nodes/srv1/lxc/200/status/stop", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+		_, _ = fmt.Fprint(w, `{"data":"UPID:srv1:00001234:00005678:65A1B2C3:vzstop:200:root@pam:"}`)
+	})
+
+	_, _, err := client.Nodes.StopLxc("srv1", 200, &StopLxcOptions{OverruleShutdown: true})
+	require.NoError(t, err)
+	require.Equal(t, "1", form.Get("overrule-shutdown"))
+}
@@ -0,0 +1,67 @@
+package proxmox
+
+import "net/http"
+
+// RoundTripperFunc is an adapter to allow the use of ordinary functions as an http.RoundTripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements the http.RoundTripper interface for RoundTripperFunc
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Middleware wraps the round trip of every API request the client sends.
+// The request it receives is already authenticated and addressed to its endpoint,
+// and it's called once per attempt when requests are retried or fail over.
+// The unauthenticated health checks of CheckEndpoints are sent without middleware.
+// Middleware must not close or read the response body unless it replaces it.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// WithMiddleware adds middleware around every request the client sends.
+// Middleware is applied in order, so the first middleware given is the outermost one and sees requests first.
+// Multiple uses of this option append to the chain.
+func WithMiddleware(middleware ...Middleware) ClientOptionFunc {
+	return func(c *Client) error {
+		for _, mw := range middleware {
+			if mw != nil {
+				c.middleware = append(c.middleware, mw)
+			}
+		}
+		return nil
+	}
+}
+
+// RequestHook returns a Middleware that calls fn with every request before it's sent.
+// It's useful for adding headers, or for logging and auditing requests. Returning an error aborts the request.
+func RequestHook(fn func(*http.Request) error) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if err := fn(req); err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// ResponseHook returns a Middleware that calls fn with every request and its response or error after it was sent.
+// It's useful for metrics and auditing.
+func ResponseHook(fn func(req *http.Request, resp *http.Response, err error)) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			fn(req, resp, err)
+			return resp, err
+		})
+	}
+}
+
+// buildTransport builds the round tripper requests are sent through, with the client's middleware around its HTTP client.
+// It's built once the options were applied, since they can replace the HTTP client and add middleware.
+func (c *Client) buildTransport() {
+	var rt http.RoundTripper = RoundTripperFunc(c.client.Do)
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		rt = c.middleware[i](rt)
+	}
+	c.transport = rt
+}
@@ -0,0 +1,93 @@
+package proxmox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithMiddleware(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" before")
+				resp, err := next.RoundTrip(req)
+				calls = append(calls, name+" after")
+				return resp, err
+			})
+		}
+	}
+
+	var audited []string
+	mux, server, client := setup(t,
+		WithMiddleware(
+			trace("outer"),
+			RequestHook(func(req *http.Request) error {
+				require.NotEmpty(t, req.Header.Get("Authorization"))
+				req.Header.Set("X-Request-ID", "abc")
+				return nil
+			}),
+			nil,
+		),
+		WithMiddleware(
+			trace("inner"),
+			ResponseHook(func(req *http.Request, resp *http.Response, err error) {
+				require.NoError(t, err)
+				audited = append(audited, fmt.Sprintf("%s %s %d", req.Method, req.URL.Path, resp.StatusCode))
+			}),
+		),
+	)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Request-ID") != "abc" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, err := fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+		if err != nil {
+			return
+		}
+	})
+
+	r, _, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Len(t, r.Data, 3)
+	require.Equal(t, []string{"outer before", "inner before", "inner after", "outer after"}, calls)
+	require.Equal(t, []string{"GET /api2/json/nodes 200"}, audited)
+}
+
+func TestRequestHookAbort(t *testing.T) {
+	errDenied := errors.New("denied by policy")
+	_, server, client := setup(t, WithMiddleware(RequestHook(func(req *http.Request) error {
+		return errDenied
+	})))
+	defer teardown(server)
+
+	_, _, err := client.Nodes.GetNodes()
+	require.ErrorIs(t, err, errDenied)
+}
+
+func TestMiddlewareSkipsHealthChecks(t *testing.T) {
+	var calls int32
+	first, second, client := setupEndpoints(t, WithMiddleware(RequestHook(func(req *http.Request) error {
+		atomic.AddInt32(&calls, 1)
+		require.NotEmpty(t, req.Header.Get("Authorization"))
+		return nil
+	})))
+	defer teardown(first)
+	defer teardown(second)
+
+	require.NoError(t, client.CheckEndpoints(context.Background()))
+	require.Equal(t, int32(0), atomic.LoadInt32(&calls))
+
+	_, _, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
@@ -1,6 +1,7 @@
 package proxmox
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
@@ -35,8 +36,13 @@ type GetNodesData struct {
 // GetNodes makes a GET request to the /nodes endpoint
 // https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes
 func (s *NodeService) GetNodes() (*GetNodesResponse, *http.Response, error) {
+	return s.GetNodesWithContext(context.Background())
+}
+
+// GetNodesWithContext is like GetNodes but uses the given context for the request
+func (s *NodeService) GetNodesWithContext(ctx context.Context) (*GetNodesResponse, *http.Response, error) {
 	u := "nodes"
-	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.GetNodes"), http.MethodGet, u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -77,8 +83,13 @@ type GetNodeStatusData struct {
 // This returns more information about a node than the /nodes endpoint
 // https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/status
 func (s *NodeService) GetNodeStatus(name string) (*GetNodeStatusResponse, *http.Response, error) {
+	return s.GetNodeStatusWithContext(context.Background(), name)
+}
+
+// GetNodeStatusWithContext is like GetNodeStatus but uses the given context for the request
+func (s *NodeService) GetNodeStatusWithContext(ctx context.Context, name string) (*GetNodeStatusResponse, *http.Response, error) {
 	u := fmt.Sprintf("nodes/%s/status", name)
-	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.GetNodeStatus"), http.MethodGet, u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -107,8 +118,13 @@ type GetNodeVersionData struct {
 // GetNodeVersion makes a GET request to the /nodes/{node}/version endpoint
 // https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/version
 func (s *NodeService) GetNodeVersion(name string) (*GetNodeVersionResponse, *http.Response, error) {
+	return s.GetNodeVersionWithContext(context.Background(), name)
+}
+
+// GetNodeVersionWithContext is like GetNodeVersion but uses the given context for the request
+func (s *NodeService) GetNodeVersionWithContext(ctx context.Context, name string) (*GetNodeVersionResponse, *http.Response, error) {
 	u := fmt.Sprintf("nodes/%s/version", name)
-	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.GetNodeVersion"), http.MethodGet, u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -150,8 +166,13 @@ type GetNodeQemuData struct {
 // GetNodeQemu makes a GET request to the /nodes/{node}/qemu endpoint
 // https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu
 func (s *NodeService) GetNodeQemu(name string) (*GetNodeQemuResponse, *http.Response, error) {
+	return s.GetNodeQemuWithContext(context.Background(), name)
+}
+
+// GetNodeQemuWithContext is like GetNodeQemu but uses the given context for the request
+func (s *NodeService) GetNodeQemuWithContext(ctx context.Context, name string) (*GetNodeQemuResponse, *http.Response, error) {
 	u := fmt.Sprintf("nodes/%s/qemu", name)
-	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.GetNodeQemu"), http.MethodGet, u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -194,8 +215,13 @@ type GetNodeLxcData struct {
 // GetNodeLxc makes a GET request to the /nodes/{node}/lxc endpoint
 // https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/lxc
 func (s *NodeService) GetNodeLxc(name string) (*GetNodeLxcResponse, *http.Response, error) {
+	return s.GetNodeLxcWithContext(context.Background(), name)
+}
+
+// GetNodeLxcWithContext is like GetNodeLxc but uses the given context for the request
+func (s *NodeService) GetNodeLxcWithContext(ctx context.Context, name string) (*GetNodeLxcResponse, *http.Response, error) {
 	u := fmt.Sprintf("nodes/%s/lxc", name)
-	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.GetNodeLxc"), http.MethodGet, u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -236,8 +262,13 @@ type GetNodeDisksListData struct {
 // GetNodeDisksList makes a GET request to the /nodes/{node}/disks/list endpoint
 // https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/disks/list
 func (s *NodeService) GetNodeDisksList(name string) (*GetNodeDisksListResponse, *http.Response, error) {
+	return s.GetNodeDisksListWithContext(context.Background(), name)
+}
+
+// GetNodeDisksListWithContext is like GetNodeDisksList but uses the given context for the request
+func (s *NodeService) GetNodeDisksListWithContext(ctx context.Context, name string) (*GetNodeDisksListResponse, *http.Response, error) {
 	u := fmt.Sprintf("nodes/%s/disks/list", name)
-	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.GetNodeDisksList"), http.MethodGet, u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -273,8 +304,13 @@ type GetNodeCertificatesInfoData struct {
 // GetNodeCertificatesInfo makes a GET request to the /nodes/{node}/certificates/info endpoint
 // https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/certificates/info
 func (s *NodeService) GetNodeCertificatesInfo(name string) (*GetNodeCertificatesInfoResponse, *http.Response, error) {
+	return s.GetNodeCertificatesInfoWithContext(context.Background(), name)
+}
+
+// GetNodeCertificatesInfoWithContext is like GetNodeCertificatesInfo but uses the given context for the request
+func (s *NodeService) GetNodeCertificatesInfoWithContext(ctx context.Context, name string) (*GetNodeCertificatesInfoResponse, *http.Response, error) {
 	u := fmt.Sprintf("nodes/%s/certificates/info", name)
-	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.GetNodeCertificatesInfo"), http.MethodGet, u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -310,8 +346,13 @@ type GetNodeStorageData struct {
 // GetNodeStorage makes a GET request to the /nodes/{node}/storage endpoint
 // https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/storage
 func (s *NodeService) GetNodeStorage(name string) (*GetNodeStorageResponse, *http.Response, error) {
+	return s.GetNodeStorageWithContext(context.Background(), name)
+}
+
+// GetNodeStorageWithContext is like GetNodeStorage but uses the given context for the request
+func (s *NodeService) GetNodeStorageWithContext(ctx context.Context, name string) (*GetNodeStorageResponse, *http.Response, error) {
 	u := fmt.Sprintf("nodes/%s/storage", name)
-	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.GetNodeStorage"), http.MethodGet, u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -344,8 +385,13 @@ type GetQemuSnapshotsData struct {
 // GetQemuSnapshots makes a GET request to the /nodes/{node}/qemu/{vmid}/snapshot endpoint
 // https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu/{vmid}/snapshot
 func (s *NodeService) GetQemuSnapshots(nodeName string, vmID int) (*GetQemuSnapshotsResponse, *http.Response, error) {
+	return s.GetQemuSnapshotsWithContext(context.Background(), nodeName, vmID)
+}
+
+// GetQemuSnapshotsWithContext is like GetQemuSnapshots but uses the given context for the request
+func (s *NodeService) GetQemuSnapshotsWithContext(ctx context.Context, nodeName string, vmID int) (*GetQemuSnapshotsResponse, *http.Response, error) {
 	u := fmt.Sprintf("nodes/%s/qemu/%d/snapshot", nodeName, vmID)
-	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.GetQemuSnapshots"), http.MethodGet, u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -377,8 +423,13 @@ type GetLxcSnapshotsData struct {
 // GetLxcSnapshots makes a GET request to the /nodes/{node}/lxc/{vmid}/snapshot endpoint
 // https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/lxc/{vmid}/snapshot
 func (s *NodeService) GetLxcSnapshots(nodeName string, vmID int) (*GetLxcSnapshotsResponse, *http.Response, error) {
+	return s.GetLxcSnapshotsWithContext(context.Background(), nodeName, vmID)
+}
+
+// GetLxcSnapshotsWithContext is like GetLxcSnapshots but uses the given context for the request
+func (s *NodeService) GetLxcSnapshotsWithContext(ctx context.Context, nodeName string, vmID int) (*GetLxcSnapshotsResponse, *http.Response, error) {
 	u := fmt.Sprintf("nodes/%s/lxc/%d/snapshot", nodeName, vmID)
-	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.GetLxcSnapshots"), http.MethodGet, u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
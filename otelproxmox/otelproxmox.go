@@ -0,0 +1,149 @@
+// Package otelproxmox instruments the go-proxmox API client with OpenTelemetry tracing and metrics.
+//
+// It creates a client span for every API call, named after the service method making it, like "NodeService.GetNodeQemu",
+// and records the duration and errors of every call.
+package otelproxmox
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	proxmox "github.com/starttoaster/go-proxmox"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name used for the tracer and meter
+const ScopeName = "github.com/starttoaster/go-proxmox/otelproxmox"
+
+// Attribute keys set on spans and metrics
+const (
+	AttributeOperation = attribute.Key("proxmox.operation")
+	AttributeEndpoint  = attribute.Key("proxmox.endpoint")
+	AttributeNode      = attribute.Key("proxmox.node")
+	AttributeVMID      = attribute.Key("proxmox.vmid")
+	AttributeMethod    = attribute.Key("http.request.method")
+	AttributeStatus    = attribute.Key("http.response.status_code")
+)
+
+// config contains the settings for an Instrumenter
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// Option configures an Instrumenter
+type Option func(*config)
+
+// WithTracerProvider sets the TracerProvider spans are created with.
+// Default: the global TracerProvider
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider sets the MeterProvider metrics are recorded with.
+// Default: the global MeterProvider
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = mp
+	}
+}
+
+// Instrumenter implements proxmox.Instrumenter with OpenTelemetry
+type Instrumenter struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+}
+
+// NewInstrumenter returns a new Instrumenter
+func NewInstrumenter(options ...Option) (*Instrumenter, error) {
+	cfg := &config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, fn := range options {
+		if fn != nil {
+			fn(cfg)
+		}
+	}
+
+	meter := cfg.meterProvider.Meter(ScopeName)
+	duration, err := meter.Float64Histogram("proxmox.client.operation.duration",
+		metric.WithDescription("Duration of Proxmox API calls"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	errs, err := meter.Int64Counter("proxmox.client.operation.errors",
+		metric.WithDescription("Number of failed Proxmox API calls"),
+		metric.WithUnit("{error}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Instrumenter{
+		tracer:   cfg.tracerProvider.Tracer(ScopeName),
+		duration: duration,
+		errors:   errs,
+	}, nil
+}
+
+// WithInstrumentation returns a client option that instruments the client with OpenTelemetry
+func WithInstrumentation(options ...Option) proxmox.ClientOptionFunc {
+	return func(c *proxmox.Client) error {
+		i, err := NewInstrumenter(options...)
+		if err != nil {
+			return err
+		}
+		return proxmox.WithInstrumenter(i)(c)
+	}
+}
+
+// StartOperation implements proxmox.Instrumenter by starting a client span for the API call
+func (i *Instrumenter) StartOperation(ctx context.Context, op proxmox.Operation) (context.Context, func(*http.Response, error)) {
+	attrs := []attribute.KeyValue{
+		AttributeOperation.String(op.Name),
+		AttributeMethod.String(op.Method),
+		AttributeEndpoint.String(op.Path),
+	}
+	if op.Node != "" {
+		attrs = append(attrs, AttributeNode.String(op.Node))
+	}
+	if op.VMID != "" {
+		attrs = append(attrs, AttributeVMID.String(op.VMID))
+	}
+
+	start := time.Now()
+	ctx, span := i.tracer.Start(ctx, op.Name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+
+	return ctx, func(resp *http.Response, err error) {
+		// Metric attributes leave out the endpoint, node and guest ID to keep cardinality low
+		metricAttrs := []attribute.KeyValue{
+			AttributeOperation.String(op.Name),
+			AttributeMethod.String(op.Method),
+		}
+		if resp != nil {
+			span.SetAttributes(AttributeStatus.Int(resp.StatusCode))
+			metricAttrs = append(metricAttrs, AttributeStatus.Int(resp.StatusCode))
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			i.errors.Add(ctx, 1, metric.WithAttributes(metricAttrs...))
+		}
+		i.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(metricAttrs...))
+		span.End()
+	}
+}
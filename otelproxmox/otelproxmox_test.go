@@ -0,0 +1,89 @@
+package otelproxmox
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	proxmox "github.com/starttoaster/go-proxmox"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithInstrumentation(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu", func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, `{"data":[]}`)
+		if err != nil {
+			return
+		}
+	})
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/100/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	client, err := proxmox.NewClient("test-token-id", "test-token",
+		proxmox.WithBaseURL(server.URL),
+		proxmox.WithHTTPClient(&http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		}),
+		WithInstrumentation(WithTracerProvider(tp), WithMeterProvider(mp)),
+	)
+	require.NoError(t, err)
+
+	// Spans are children of the span in the caller's context
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	_, _, err = client.Nodes.GetNodeQemuWithContext(ctx, "srv1")
+	require.NoError(t, err)
+	_, _, err = client.Nodes.GetQemuSnapshotsWithContext(ctx, "srv1", 100)
+	require.Error(t, err)
+	parent.End()
+
+	ended := spans.Ended()
+	require.Len(t, ended, 3)
+
+	require.Equal(t, "NodeService.GetNodeQemu", ended[0].Name())
+	require.Equal(t, parent.SpanContext().SpanID(), ended[0].Parent().SpanID())
+	require.Contains(t, ended[0].Attributes(), AttributeNode.String("srv1"))
+	require.Contains(t, ended[0].Attributes(), AttributeEndpoint.String("nodes/srv1/qemu"))
+	require.Contains(t, ended[0].Attributes(), AttributeStatus.Int(http.StatusOK))
+	require.Equal(t, codes.Unset, ended[0].Status().Code)
+
+	require.Equal(t, "NodeService.GetQemuSnapshots", ended[1].Name())
+	require.Contains(t, ended[1].Attributes(), AttributeVMID.String("100"))
+	require.Equal(t, codes.Error, ended[1].Status().Code)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	metrics := map[string]metricdata.Metrics{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+
+	duration := metrics["proxmox.client.operation.duration"].Data.(metricdata.Histogram[float64])
+	require.Len(t, duration.DataPoints, 2)
+
+	errs := metrics["proxmox.client.operation.errors"].Data.(metricdata.Sum[int64])
+	require.Len(t, errs.DataPoints, 1)
+	require.Equal(t, int64(1), errs.DataPoints[0].Value)
+	op, ok := errs.DataPoints[0].Attributes.Value(AttributeOperation)
+	require.True(t, ok)
+	require.Equal(t, "NodeService.GetQemuSnapshots", op.AsString())
+}
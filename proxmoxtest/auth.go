@@ -0,0 +1,59 @@
+package proxmoxtest
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ticket is a ticket the server issued to a user
+type ticket struct {
+	username  string
+	csrfToken string
+}
+
+// authorized reports whether a request carries a known API token, or a known ticket with a matching CSRF prevention token.
+// The caller must hold s.mu.
+func (s *Server) authorized(r *http.Request) bool {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "PVEAPIToken=") {
+		tokenID, secret, ok := strings.Cut(strings.TrimPrefix(auth, "PVEAPIToken="), "=")
+		return ok && s.tokens[tokenID] == secret && secret != ""
+	}
+
+	cookie, err := r.Cookie("PVEAuthCookie")
+	if err != nil {
+		return false
+	}
+	t, ok := s.tickets[cookie.Value]
+	if !ok {
+		return false
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return r.Header.Get("CSRFPreventionToken") == t.csrfToken
+	}
+
+	return true
+}
+
+// handleTicket handles POST /access/ticket, issuing a ticket for a known user
+func (s *Server) handleTicket(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	username, password := r.PostForm.Get("username"), r.PostForm.Get("password")
+	if expected, ok := s.users[username]; !ok || expected != password {
+		writeError(w, http.StatusUnauthorized, "authentication failure")
+		return
+	}
+
+	s.seq++
+	t := ticket{
+		username:  username,
+		csrfToken: fmt.Sprintf("proxmoxtest-csrf-%d", s.seq),
+	}
+	value := fmt.Sprintf("PVE:%s:PROXMOXTEST%08X", username, s.seq)
+	s.tickets[value] = t
+
+	writeData(w, map[string]string{
+		"ticket":              value,
+		"CSRFPreventionToken": t.csrfToken,
+		"username":            username,
+	})
+}
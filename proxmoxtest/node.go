@@ -0,0 +1,265 @@
+package proxmoxtest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	proxmox "github.com/starttoaster/go-proxmox"
+)
+
+// Node is a node of the fake cluster. Its methods seed the node's state and are safe for concurrent use.
+type Node struct {
+	server *Server
+	name   string
+	ip     string
+
+	status  proxmox.GetNodeStatusData
+	version proxmox.GetNodeVersionData
+	storage []proxmox.GetNodeStorageData
+	qemu    map[int]*qemuGuest
+	lxc     map[int]*lxcGuest
+}
+
+// qemuGuest is a QEMU virtual machine on a fake node
+type qemuGuest struct {
+	data      proxmox.GetNodeQemuData
+	snapshots []proxmox.GetQemuSnapshotsData
+	paused    bool
+
+	// config holds the options of the VM config, pending the changes applied on the next start
+	// and pendingDelete the options removed on the next start
+	config        map[string]string
+	pending       map[string]string
+	pendingDelete map[string]bool
+}
+
+// newQemuGuest returns a VM with a config matching its data, plus the given options
+func newQemuGuest(vm proxmox.GetNodeQemuData, options map[string]string) *qemuGuest {
+	config := map[string]string{"name": vm.Name}
+	if vm.CPUs > 0 {
+		config["cores"] = strconv.Itoa(vm.CPUs)
+	}
+	if vm.MaxMem > 0 {
+		config["memory"] = strconv.Itoa(vm.MaxMem >> 20)
+	}
+	if vm.Tags != "" {
+		config["tags"] = vm.Tags
+	}
+	for k, v := range options {
+		config[k] = v
+	}
+	return &qemuGuest{data: vm, config: config, pending: map[string]string{}, pendingDelete: map[string]bool{}}
+}
+
+// digest returns the SHA-1 digest of the VM config, including its pending changes like Proxmox
+func (vm *qemuGuest) digest() string {
+	var lines []string
+	for k, v := range vm.config {
+		lines = append(lines, k+": "+v)
+	}
+	for k, v := range vm.pending {
+		lines = append(lines, "pending "+k+": "+v)
+	}
+	for k := range vm.pendingDelete {
+		lines = append(lines, "delete "+k)
+	}
+	sort.Strings(lines)
+	sum := sha1.Sum([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// set sets a config option and updates the VM's data to match
+func (vm *qemuGuest) set(key, value string) {
+	vm.config[key] = value
+	switch key {
+	case "name":
+		vm.data.Name = value
+	case "tags":
+		vm.data.Tags = value
+	case "cores", "sockets":
+		cores, _ := strconv.Atoi(vm.config["cores"])
+		sockets, err := strconv.Atoi(vm.config["sockets"])
+		if err != nil {
+			sockets = 1
+		}
+		vm.data.CPUs = cores * sockets
+	case "memory":
+		memory, _ := strconv.Atoi(value)
+		vm.data.MaxMem = memory << 20
+	}
+}
+
+// applyPending applies the pending changes, like Proxmox does when the VM starts
+func (vm *qemuGuest) applyPending() {
+	for k, v := range vm.pending {
+		vm.set(k, v)
+	}
+	for k := range vm.pendingDelete {
+		delete(vm.config, k)
+	}
+	vm.pending = map[string]string{}
+	vm.pendingDelete = map[string]bool{}
+}
+
+// lxcGuest is an LXC container on a fake node
+type lxcGuest struct {
+	data      proxmox.GetNodeLxcData
+	snapshots []proxmox.GetLxcSnapshotsData
+}
+
+// defaultVersion is the Proxmox VE version fake nodes report unless set with Node.SetVersion
+var defaultVersion = proxmox.GetNodeVersionData{Release: "8.2", RepoID: "faa83925c9641325", Version: "8.2.4"}
+
+// newNode returns a new online node with a plausible status
+func newNode(s *Server, name string) *Node {
+	return &Node{
+		server: s,
+		name:   name,
+		ip:     "127.0.0.1",
+		status: proxmox.GetNodeStatusData{
+			CPU:        0.01,
+			CPUInfo:    proxmox.CPUInfo{Cores: 4, CPUs: 8, Sockets: 1, Model: "proxmoxtest CPU"},
+			LoadAvg:    []string{"0.00", "0.00", "0.00"},
+			Memory:     proxmox.Memory{Free: 12 << 30, Total: 16 << 30, Used: 4 << 30},
+			RootFs:     proxmox.RootFS{Avail: 90 << 30, Free: 90 << 30, Total: 100 << 30, Used: 10 << 30},
+			PveVersion: "pve-manager/8.2.4/faa83925c9641325",
+			Uptime:     3600,
+		},
+		version: defaultVersion,
+		qemu:    map[int]*qemuGuest{},
+		lxc:     map[int]*lxcGuest{},
+	}
+}
+
+// Name returns the name of the node
+func (n *Node) Name() string {
+	return n.name
+}
+
+// SetIP sets the IP address reported for the node in the cluster status. Default: 127.0.0.1
+func (n *Node) SetIP(ip string) *Node {
+	n.server.mu.Lock()
+	defer n.server.mu.Unlock()
+	n.ip = ip
+	return n
+}
+
+// SetStatus replaces the status returned for the node
+func (n *Node) SetStatus(status proxmox.GetNodeStatusData) *Node {
+	n.server.mu.Lock()
+	defer n.server.mu.Unlock()
+	n.status = status
+	return n
+}
+
+// SetVersion replaces the version returned for the node
+func (n *Node) SetVersion(version proxmox.GetNodeVersionData) *Node {
+	n.server.mu.Lock()
+	defer n.server.mu.Unlock()
+	n.version = version
+	return n
+}
+
+// AddStorage adds a storage to the node
+func (n *Node) AddStorage(storage proxmox.GetNodeStorageData) *Node {
+	n.server.mu.Lock()
+	defer n.server.mu.Unlock()
+	n.storage = append(n.storage, storage)
+	return n
+}
+
+// AddQemu adds a QEMU virtual machine to the node. VMID must be a number. An empty status defaults to "stopped".
+func (n *Node) AddQemu(vm proxmox.GetNodeQemuData) *Node {
+	vmid := mustVMID(vm.VMID)
+	if vm.Status == "" {
+		vm.Status = "stopped"
+	}
+
+	n.server.mu.Lock()
+	defer n.server.mu.Unlock()
+	n.qemu[vmid] = newQemuGuest(vm, nil)
+	return n
+}
+
+// AddLxc adds an LXC container to the node. VMID must be a number. An empty status defaults to "stopped".
+func (n *Node) AddLxc(ct proxmox.GetNodeLxcData) *Node {
+	vmid := mustVMID(ct.VMID)
+	if ct.Status == "" {
+		ct.Status = "stopped"
+	}
+	if ct.Type == "" {
+		ct.Type = "lxc"
+	}
+
+	n.server.mu.Lock()
+	defer n.server.mu.Unlock()
+	n.lxc[vmid] = &lxcGuest{data: ct}
+	return n
+}
+
+// AddQemuSnapshot adds a snapshot to a QEMU virtual machine of the node. It panics if the virtual machine doesn't exist.
+func (n *Node) AddQemuSnapshot(vmid int, snapshot proxmox.GetQemuSnapshotsData) *Node {
+	n.server.mu.Lock()
+	defer n.server.mu.Unlock()
+
+	vm, ok := n.qemu[vmid]
+	if !ok {
+		panic(fmt.Sprintf("proxmoxtest: QEMU VM %d does not exist on node %s", vmid, n.name))
+	}
+	vm.snapshots = append(vm.snapshots, snapshot)
+	return n
+}
+
+// AddLxcSnapshot adds a snapshot to an LXC container of the node. It panics if the container doesn't exist.
+func (n *Node) AddLxcSnapshot(vmid int, snapshot proxmox.GetLxcSnapshotsData) *Node {
+	n.server.mu.Lock()
+	defer n.server.mu.Unlock()
+
+	ct, ok := n.lxc[vmid]
+	if !ok {
+		panic(fmt.Sprintf("proxmoxtest: LXC container %d does not exist on node %s", vmid, n.name))
+	}
+	ct.snapshots = append(ct.snapshots, snapshot)
+	return n
+}
+
+// AddTask adds a task to the node and returns its UPID.
+// If exitStatus is empty the task keeps running until it's finished with Server.FinishTask.
+func (n *Node) AddTask(taskType, id, exitStatus string, log ...string) string {
+	n.server.mu.Lock()
+	defer n.server.mu.Unlock()
+	return n.server.startTask(n.name, taskType, id, "root@pam", exitStatus, log...)
+}
+
+// qemuIDs returns the IDs of the node's QEMU virtual machines in ascending order. The caller must hold s.mu.
+func (n *Node) qemuIDs() []int {
+	ids := make([]int, 0, len(n.qemu))
+	for id := range n.qemu {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// lxcIDs returns the IDs of the node's LXC containers in ascending order. The caller must hold s.mu.
+func (n *Node) lxcIDs() []int {
+	ids := make([]int, 0, len(n.lxc))
+	for id := range n.lxc {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// mustVMID parses a guest ID, panicking if it isn't a number
+func mustVMID(vmid proxmox.IntOrString) int {
+	id, err := strconv.Atoi(string(vmid))
+	if err != nil {
+		panic(fmt.Sprintf("proxmoxtest: invalid VMID %q", vmid))
+	}
+	return id
+}
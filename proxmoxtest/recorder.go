@@ -0,0 +1,331 @@
+package proxmoxtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RecorderMode sets whether a Recorder records real responses or replays recorded ones
+type RecorderMode int
+
+const (
+	// ModeReplay replays responses from the cassette without sending any requests
+	ModeReplay RecorderMode = iota
+
+	// ModeRecord sends requests to the real server and records them, replacing the cassette when saved
+	ModeRecord
+
+	// ModeReplayOrRecord replays if the cassette file exists and records otherwise
+	ModeReplayOrRecord
+)
+
+// redacted replaces secrets in recorded interactions
+const redacted = "REDACTED"
+
+// ErrNoCassette is returned when replaying a cassette file that doesn't exist
+var ErrNoCassette = errors.New("cassette does not exist")
+
+// Cassette holds recorded request and response pairs
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a recorded request. Headers and the host are not recorded, so secrets in them are never stored,
+// and cassettes can be replayed against any base URL.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// RecordedResponse is a recorded response. JSON bodies are stored as JSON to keep cassettes readable, other bodies as text.
+type RecordedResponse struct {
+	StatusCode  int             `json:"status_code"`
+	Status      string          `json:"status"`
+	ContentType string          `json:"content_type,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	RawBody     string          `json:"raw_body,omitempty"`
+}
+
+// RecorderOption configures a Recorder
+type RecorderOption func(*Recorder)
+
+// WithTransport sets the transport requests are sent with when recording. Default: http.DefaultTransport
+func WithTransport(rt http.RoundTripper) RecorderOption {
+	return func(r *Recorder) {
+		r.transport = rt
+	}
+}
+
+// WithScrubber adds a function that removes secrets from interactions before they're recorded.
+// Requests are scrubbed the same way before being matched during replay.
+// Passwords, tickets and CSRF prevention tokens from the /access/ticket endpoint are always scrubbed.
+func WithScrubber(fn func(*Interaction)) RecorderOption {
+	return func(r *Recorder) {
+		r.scrubbers = append(r.scrubbers, fn)
+	}
+}
+
+// Recorder is an http.RoundTripper that records real Proxmox API requests and responses to a cassette file,
+// and replays them deterministically. Use it with proxmox.WithHTTPClient to build regression tests from real clusters.
+type Recorder struct {
+	path      string
+	mode      RecorderMode
+	transport http.RoundTripper
+	scrubbers []func(*Interaction)
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewRecorder returns a Recorder for the cassette file at path. In replay mode the cassette is loaded immediately.
+func NewRecorder(path string, mode RecorderMode, options ...RecorderOption) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		mode:      mode,
+		transport: http.DefaultTransport,
+		cassette:  &Cassette{},
+	}
+	for _, fn := range options {
+		if fn != nil {
+			fn(r)
+		}
+	}
+
+	if r.mode == ModeReplayOrRecord {
+		r.mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			r.mode = ModeReplay
+		}
+	}
+
+	if r.mode == ModeReplay {
+		b, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNoCassette, path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading cassette: %w", err)
+		}
+		if err := json.Unmarshal(b, r.cassette); err != nil {
+			return nil, fmt.Errorf("error parsing cassette %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+
+	return r, nil
+}
+
+// Mode returns whether the recorder is recording or replaying
+func (r *Recorder) Mode() RecorderMode {
+	return r.mode
+}
+
+// HTTPClient returns an HTTP client that sends its requests through the recorder
+func (r *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip implements the http.RoundTripper interface for Recorder
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, req, err := r.recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, recorded)
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	i := &Interaction{
+		Request: *recorded,
+		Response: RecordedResponse{
+			StatusCode:  resp.StatusCode,
+			Status:      resp.Status,
+			ContentType: resp.Header.Get("Content-Type"),
+		},
+	}
+	if json.Valid(body) {
+		i.Response.Body = json.RawMessage(body)
+	} else {
+		i.Response.RawBody = string(body)
+	}
+	r.scrub(i)
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, i)
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// Save writes the recorded interactions to the cassette file. It does nothing when replaying.
+func (r *Recorder) Save() error {
+	if r.mode == ModeReplay {
+		return nil
+	}
+
+	r.mu.Lock()
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(b, '\n'), 0o644)
+}
+
+// recordRequest records a request for the cassette and returns the request to send on.
+// A RoundTripper must not modify the caller's request, so the body is recorded from a copy made with GetBody,
+// or read into a buffer that a copy of the request is sent with.
+func (r *Recorder) recordRequest(req *http.Request) (*RecordedRequest, *http.Request, error) {
+	recorded := &RecordedRequest{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.RawQuery,
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		var body []byte
+		var err error
+		if req.GetBody != nil {
+			body, err = readBody(req.GetBody())
+		} else {
+			body, err = readBody(req.Body, nil)
+			sent := req.Clone(req.Context())
+			sent.Body = io.NopCloser(bytes.NewReader(body))
+			req = sent
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		recorded.Body = string(body)
+	}
+
+	// Scrub the request on its own so replayed requests are matched against their scrubbed recordings
+	i := &Interaction{Request: *recorded}
+	r.scrub(i)
+	return &i.Request, req, nil
+}
+
+// readBody reads and closes a request body, or returns the error GetBody returned instead of one
+func readBody(body io.ReadCloser, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = body.Close() }()
+	return io.ReadAll(body)
+}
+
+// replay returns the response of the first unused interaction matching the request.
+// Once all matching interactions were used, the last one is replayed again, which keeps polling loops working.
+func (r *Recorder) replay(req *http.Request, recorded *RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var match *Interaction
+	for idx, i := range r.cassette.Interactions {
+		if i.Request != *recorded {
+			continue
+		}
+		match = i
+		if !r.used[idx] {
+			r.used[idx] = true
+			break
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("no recorded interaction for %s %s?%s in cassette %s", recorded.Method, recorded.Path, recorded.Query, r.path)
+	}
+
+	body := []byte(match.Response.RawBody)
+	if len(match.Response.Body) > 0 {
+		body = match.Response.Body
+	}
+
+	header := http.Header{}
+	if match.Response.ContentType != "" {
+		header.Set("Content-Type", match.Response.ContentType)
+	}
+
+	return &http.Response{
+		StatusCode:    match.Response.StatusCode,
+		Status:        match.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// scrub removes secrets from an interaction
+func (r *Recorder) scrub(i *Interaction) {
+	scrubTicket(i)
+	for _, fn := range r.scrubbers {
+		fn(i)
+	}
+}
+
+// scrubTicket removes the password from ticket requests, and the ticket and CSRF prevention token from their responses
+func scrubTicket(i *Interaction) {
+	if !strings.HasSuffix(i.Request.Path, "/access/ticket") {
+		return
+	}
+
+	if form, err := url.ParseQuery(i.Request.Body); err == nil && form.Has("password") {
+		form.Set("password", redacted)
+		i.Request.Body = form.Encode()
+	}
+
+	if len(i.Response.Body) == 0 {
+		return
+	}
+	var envelope map[string]map[string]interface{}
+	if err := json.Unmarshal(i.Response.Body, &envelope); err != nil || envelope["data"] == nil {
+		return
+	}
+	for _, key := range []string{"ticket", "CSRFPreventionToken"} {
+		if _, ok := envelope["data"][key]; ok {
+			envelope["data"][key] = redacted
+		}
+	}
+	if b, err := json.Marshal(envelope); err == nil {
+		i.Response.Body = b
+	}
+}
@@ -0,0 +1,130 @@
+package proxmoxtest
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	proxmox "github.com/starttoaster/go-proxmox"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	s := NewServer()
+	seed(s)
+	s.AddUser("admin@pve", "hunter2")
+	cassette := filepath.Join(t.TempDir(), "cassettes", "nodes.json")
+
+	// Record against the fake server, with a token and a password client
+	rec, err := NewRecorder(cassette, ModeReplayOrRecord, WithTransport(s.HTTPClient().Transport))
+	require.NoError(t, err)
+	require.Equal(t, ModeRecord, rec.Mode())
+
+	tokenClient, err := proxmox.NewClient(DefaultTokenID, DefaultToken, proxmox.WithBaseURL(s.URL), proxmox.WithHTTPClient(rec.HTTPClient()))
+	require.NoError(t, err)
+	passwordClient, err := proxmox.NewClientWithPassword("admin@pve", "hunter2", proxmox.WithBaseURL(s.URL), proxmox.WithHTTPClient(rec.HTTPClient()))
+	require.NoError(t, err)
+
+	recordedNodes, _, err := tokenClient.Nodes.GetNodes()
+	require.NoError(t, err)
+	recordedVMs, _, err := passwordClient.Nodes.GetNodeQemu("srv1")
+	require.NoError(t, err)
+	_, _, err = tokenClient.Nodes.GetQemuSnapshots("srv1", 999)
+	require.True(t, proxmox.IsNotFound(err))
+
+	require.NoError(t, rec.Save())
+	s.Close()
+
+	// Secrets never end up in the cassette
+	b, err := os.ReadFile(cassette)
+	require.NoError(t, err)
+	for _, secret := range []string{DefaultToken, "hunter2", "PVE:admin@pve:", "proxmoxtest-csrf"} {
+		require.False(t, strings.Contains(string(b), secret), secret)
+	}
+
+	// Replay without the server
+	rec, err = NewRecorder(cassette, ModeReplayOrRecord)
+	require.NoError(t, err)
+	require.Equal(t, ModeReplay, rec.Mode())
+
+	tokenClient, err = proxmox.NewClient("other@pam!token", "other-secret", proxmox.WithBaseURL("https://pve.invalid:8006"), proxmox.WithHTTPClient(rec.HTTPClient()))
+	require.NoError(t, err)
+	passwordClient, err = proxmox.NewClientWithPassword("admin@pve", "hunter2", proxmox.WithBaseURL("https://pve.invalid:8006"), proxmox.WithHTTPClient(rec.HTTPClient()))
+	require.NoError(t, err)
+
+	nodes, _, err := tokenClient.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Equal(t, recordedNodes, nodes)
+	vms, _, err := passwordClient.Nodes.GetNodeQemu("srv1")
+	require.NoError(t, err)
+	require.Equal(t, recordedVMs, vms)
+	_, resp, err := tokenClient.Nodes.GetQemuSnapshots("srv1", 999)
+	require.True(t, proxmox.IsNotFound(err))
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	// Requests that weren't recorded fail
+	_, _, err = tokenClient.Nodes.GetNodeLxc("srv1")
+	require.ErrorContains(t, err, "no recorded interaction")
+}
+
+func TestRecorderMissingCassette(t *testing.T) {
+	_, err := NewRecorder(filepath.Join(t.TempDir(), "missing.json"), ModeReplay)
+	require.ErrorIs(t, err, ErrNoCassette)
+}
+
+func TestRecorderScrubber(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	seed(s)
+	cassette := filepath.Join(t.TempDir(), "nodes.json")
+
+	rec, err := NewRecorder(cassette, ModeRecord,
+		WithTransport(s.HTTPClient().Transport),
+		WithScrubber(func(i *Interaction) {
+			i.Request.Path = strings.ReplaceAll(i.Request.Path, "srv1", "node1")
+		}),
+	)
+	require.NoError(t, err)
+
+	c, err := s.Client(proxmox.WithHTTPClient(rec.HTTPClient()))
+	require.NoError(t, err)
+	_, _, err = c.Nodes.GetNodeStatus("srv1")
+	require.NoError(t, err)
+	require.NoError(t, rec.Save())
+
+	b, err := os.ReadFile(cassette)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"path": "/api2/json/nodes/node1/status"`)
+}
+
+func TestRecorderKeepsRequest(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	seed(s)
+	cassette := filepath.Join(t.TempDir(), "start.json")
+
+	rec, err := NewRecorder(cassette, ModeRecord, WithTransport(s.HTTPClient().Transport))
+	require.NoError(t, err)
+
+	// Bodies are recorded with and without GetBody, without replacing the body of the caller's request
+	for _, body := range []io.Reader{strings.NewReader("timeout=30"), io.NopCloser(strings.NewReader("timeout=30"))} {
+		req, err := http.NewRequest(http.MethodPost, s.URL+"/api2/json/nodes/srv1/qemu/101/status/start", body)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "PVEAPIToken="+DefaultTokenID+"="+DefaultToken)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		reqBody := req.Body
+
+		resp, err := rec.RoundTrip(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		require.True(t, req.Body == reqBody)
+	}
+	require.NoError(t, rec.Save())
+
+	b, err := os.ReadFile(cassette)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(b), `"body": "timeout=30"`))
+}
@@ -0,0 +1,653 @@
+package proxmoxtest
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	proxmox "github.com/starttoaster/go-proxmox"
+)
+
+// registerRoutes adds the API endpoints the server implements
+func (s *Server) registerRoutes() {
+	s.handle(http.MethodPost, "access/ticket", s.handleTicket)
+	s.handle(http.MethodGet, "version", s.getVersion)
+
+	s.handle(http.MethodGet, "cluster/status", s.getClusterStatus)
+	s.handle(http.MethodGet, "cluster/resources", s.getClusterResources)
+
+	s.handle(http.MethodGet, "nodes", s.getNodes)
+	s.handle(http.MethodGet, "nodes/{node}/status", s.getNodeStatus)
+	s.handle(http.MethodGet, "nodes/{node}/version", s.getNodeVersion)
+	s.handle(http.MethodGet, "nodes/{node}/storage", s.getNodeStorage)
+	s.handle(http.MethodGet, "nodes/{node}/disks/list", s.getNodeEmptyList)
+	s.handle(http.MethodGet, "nodes/{node}/certificates/info", s.getNodeEmptyList)
+	s.handle(http.MethodGet, "nodes/{node}/qemu", s.getNodeQemu)
+	s.handle(http.MethodPost, "nodes/{node}/qemu", s.createQemu)
+	s.handle(http.MethodGet, "nodes/{node}/qemu/{vmid}/config", s.getQemuConfig)
+	s.handle(http.MethodPut, "nodes/{node}/qemu/{vmid}/config", s.updateQemuConfig)
+	s.handle(http.MethodPost, "nodes/{node}/qemu/{vmid}/config", s.updateQemuConfig)
+	s.handle(http.MethodGet, "nodes/{node}/qemu/{vmid}/pending", s.getQemuPending)
+	s.handle(http.MethodGet, "nodes/{node}/qemu/{vmid}/snapshot", s.getQemuSnapshots)
+	s.handle(http.MethodGet, "nodes/{node}/qemu/{vmid}/status/current", s.getQemuStatusCurrent)
+	s.handle(http.MethodPost, "nodes/{node}/qemu/{vmid}/status/{action}", s.postQemuStatus)
+	s.handle(http.MethodGet, "nodes/{node}/lxc", s.getNodeLxc)
+	s.handle(http.MethodGet, "nodes/{node}/lxc/{vmid}/snapshot", s.getLxcSnapshots)
+	s.handle(http.MethodGet, "nodes/{node}/lxc/{vmid}/status/current", s.getLxcStatusCurrent)
+	s.handle(http.MethodPost, "nodes/{node}/lxc/{vmid}/status/{action}", s.postLxcStatus)
+	s.handle(http.MethodGet, "nodes/{node}/tasks/{upid}/status", s.getTaskStatus)
+	s.handle(http.MethodGet, "nodes/{node}/tasks/{upid}/log", s.getTaskLog)
+}
+
+// lookupNode returns the node from the path parameters, or writes the error Proxmox responds with for unknown nodes
+func (s *Server) lookupNode(w http.ResponseWriter, params map[string]string) (*Node, bool) {
+	n := s.node(params["node"])
+	if n == nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("hostname lookup '%s' failed - failed to get address info for: %s: Name or service not known", params["node"], params["node"]))
+		return nil, false
+	}
+	return n, true
+}
+
+// lookupQemu returns the node and QEMU virtual machine from the path parameters, or writes the error Proxmox responds with
+func (s *Server) lookupQemu(w http.ResponseWriter, params map[string]string) (*Node, *qemuGuest, bool) {
+	n, ok := s.lookupNode(w, params)
+	if !ok {
+		return nil, nil, false
+	}
+	vmid, err := strconv.Atoi(params["vmid"])
+	if err != nil {
+		writeParamError(w, "vmid", "type check ('integer') failed - got '"+params["vmid"]+"'")
+		return nil, nil, false
+	}
+	vm, ok := n.qemu[vmid]
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Configuration file 'nodes/%s/qemu-server/%d.conf' does not exist", n.name, vmid))
+		return nil, nil, false
+	}
+	return n, vm, true
+}
+
+// lookupLxc returns the node and LXC container from the path parameters, or writes the error Proxmox responds with
+func (s *Server) lookupLxc(w http.ResponseWriter, params map[string]string) (*Node, *lxcGuest, bool) {
+	n, ok := s.lookupNode(w, params)
+	if !ok {
+		return nil, nil, false
+	}
+	vmid, err := strconv.Atoi(params["vmid"])
+	if err != nil {
+		writeParamError(w, "vmid", "type check ('integer') failed - got '"+params["vmid"]+"'")
+		return nil, nil, false
+	}
+	ct, ok := n.lxc[vmid]
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Configuration file 'nodes/%s/lxc/%d.conf' does not exist", n.name, vmid))
+		return nil, nil, false
+	}
+	return n, ct, true
+}
+
+// getVersion handles GET /version, reporting the version of the first node
+func (s *Server) getVersion(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	version := defaultVersion
+	if len(s.nodes) > 0 {
+		version = s.nodes[0].version
+	}
+	writeData(w, version)
+}
+
+// getClusterStatus handles GET /cluster/status
+func (s *Server) getClusterStatus(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	quorate, version, online := 1, 1, 1
+	data := []proxmox.GetClusterStatusData{
+		{ID: "cluster", Name: "proxmoxtest", Type: "cluster", Quorate: &quorate, Version: &version},
+	}
+	for i, n := range s.nodes {
+		ip, nodeID, local := n.ip, i+1, 0
+		if i == 0 {
+			local = 1
+		}
+		data = append(data, proxmox.GetClusterStatusData{
+			ID:     "node/" + n.name,
+			Name:   n.name,
+			Type:   "node",
+			IP:     &ip,
+			NodeID: &nodeID,
+			Local:  &local,
+			Online: &online,
+		})
+	}
+	writeData(w, data)
+}
+
+// getClusterResources handles GET /cluster/resources, with the optional type filter
+func (s *Server) getClusterResources(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	filter := r.Form.Get("type")
+	data := []proxmox.GetClusterResourcesData{}
+	for _, n := range s.nodes {
+		if filter == "" || filter == "node" {
+			maxCPU, maxMem, mem := n.status.CPUInfo.CPUs, n.status.Memory.Total, n.status.Memory.Used
+			data = append(data, proxmox.GetClusterResourcesData{
+				ID: "node/" + n.name, Node: n.name, Status: "online", Type: "node",
+				MaxCPU: &maxCPU, MaxMem: &maxMem, Mem: &mem,
+			})
+		}
+		if filter == "" || filter == "vm" {
+			for _, id := range n.qemuIDs() {
+				vm := n.qemu[id].data
+				name, vmid, maxMem, maxDisk, tags := vm.Name, vm.VMID, vm.MaxMem, vm.MaxDisk, vm.Tags
+				data = append(data, proxmox.GetClusterResourcesData{
+					ID: fmt.Sprintf("qemu/%d", id), Node: n.name, Status: vm.Status, Type: "qemu",
+					Name: &name, VMID: &vmid, MaxMem: &maxMem, MaxDisk: &maxDisk, Tags: &tags,
+				})
+			}
+			for _, id := range n.lxcIDs() {
+				ct := n.lxc[id].data
+				name, vmid, maxMem, maxDisk, tags := ct.Name, ct.VMID, ct.MaxMem, ct.MaxDisk, ct.Tags
+				data = append(data, proxmox.GetClusterResourcesData{
+					ID: fmt.Sprintf("lxc/%d", id), Node: n.name, Status: ct.Status, Type: "lxc",
+					Name: &name, VMID: &vmid, MaxMem: &maxMem, MaxDisk: &maxDisk, Tags: &tags,
+				})
+			}
+		}
+		if filter == "" || filter == "storage" {
+			for _, st := range n.storage {
+				storage, content, shared := st.Storage, st.Content, st.Shared
+				data = append(data, proxmox.GetClusterResourcesData{
+					ID: fmt.Sprintf("storage/%s/%s", n.name, st.Storage), Node: n.name, Status: "available", Type: "storage",
+					Storage: &storage, Content: &content, Shared: &shared,
+				})
+			}
+		}
+	}
+	writeData(w, data)
+}
+
+// getNodes handles GET /nodes
+func (s *Server) getNodes(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	data := []proxmox.GetNodesData{}
+	for _, n := range s.nodes {
+		data = append(data, proxmox.GetNodesData{
+			CPU:     n.status.CPU,
+			Disk:    n.status.RootFs.Used,
+			ID:      "node/" + n.name,
+			MaxCPU:  n.status.CPUInfo.CPUs,
+			MaxDisk: n.status.RootFs.Total,
+			MaxMem:  n.status.Memory.Total,
+			Mem:     n.status.Memory.Used,
+			Node:    n.name,
+			Status:  "online",
+			Type:    "node",
+			Uptime:  n.status.Uptime,
+		})
+	}
+	writeData(w, data)
+}
+
+// getNodeStatus handles GET /nodes/{node}/status
+func (s *Server) getNodeStatus(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	if n, ok := s.lookupNode(w, params); ok {
+		writeData(w, n.status)
+	}
+}
+
+// getNodeVersion handles GET /nodes/{node}/version
+func (s *Server) getNodeVersion(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	if n, ok := s.lookupNode(w, params); ok {
+		writeData(w, n.version)
+	}
+}
+
+// getNodeStorage handles GET /nodes/{node}/storage
+func (s *Server) getNodeStorage(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	if n, ok := s.lookupNode(w, params); ok {
+		writeData(w, append([]proxmox.GetNodeStorageData{}, n.storage...))
+	}
+}
+
+// getNodeEmptyList handles node endpoints the fake cluster has no state for
+func (s *Server) getNodeEmptyList(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	if _, ok := s.lookupNode(w, params); ok {
+		writeData(w, []interface{}{})
+	}
+}
+
+// getNodeQemu handles GET /nodes/{node}/qemu
+func (s *Server) getNodeQemu(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	n, ok := s.lookupNode(w, params)
+	if !ok {
+		return
+	}
+	data := []proxmox.GetNodeQemuData{}
+	for _, id := range n.qemuIDs() {
+		data = append(data, n.qemu[id].data)
+	}
+	writeData(w, data)
+}
+
+// createQemu handles POST /nodes/{node}/qemu, adding a stopped VM, or a running one if start is set,
+// and responding with the UPID of a finished task
+func (s *Server) createQemu(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	n, ok := s.lookupNode(w, params)
+	if !ok {
+		return
+	}
+
+	vmid, err := strconv.Atoi(r.Form.Get("vmid"))
+	if err != nil {
+		writeParamError(w, "vmid", "type check ('integer') failed - got '"+r.Form.Get("vmid")+"'")
+		return
+	}
+	for _, other := range s.nodes {
+		if _, exists := other.qemu[vmid]; exists {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("unable to create VM %d - VM %d already exists on node '%s'", vmid, vmid, other.name))
+			return
+		}
+		if _, exists := other.lxc[vmid]; exists {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("unable to create VM %d - CT %d already exists on node '%s'", vmid, vmid, other.name))
+			return
+		}
+	}
+
+	cores, sockets, memory := formInt(r, "cores", 1), formInt(r, "sockets", 1), formInt(r, "memory", 512)
+	vm := proxmox.GetNodeQemuData{
+		VMID:   proxmox.IntOrString(strconv.Itoa(vmid)),
+		Name:   r.Form.Get("name"),
+		CPUs:   cores * sockets,
+		MaxMem: memory << 20,
+		Status: "stopped",
+		Tags:   r.Form.Get("tags"),
+	}
+	if vm.Name == "" {
+		vm.Name = fmt.Sprintf("VM %d", vmid)
+	}
+	if r.Form.Get("start") == "1" {
+		vm.Status = "running"
+	}
+	options := map[string]string{}
+	for k := range r.PostForm {
+		if k != "vmid" && k != "start" && k != "pool" {
+			options[k] = r.PostForm.Get(k)
+		}
+	}
+	n.qemu[vmid] = newQemuGuest(vm, options)
+
+	writeData(w, s.startTask(n.name, "qmcreate", strconv.Itoa(vmid), "root@pam", "OK"))
+}
+
+// qemuHotOptions are the options the fake server changes on running VMs, changes to other options become pending
+var qemuHotOptions = map[string]bool{"name": true, "description": true, "tags": true, "onboot": true, "protection": true}
+
+// qemuUpdateParams are the parameters of a config update that aren't options
+var qemuUpdateParams = map[string]bool{"delete": true, "revert": true, "digest": true, "force": true, "skiplock": true, "background_delay": true}
+
+// getQemuConfig handles GET /nodes/{node}/qemu/{vmid}/config, applying pending changes unless current is set like Proxmox
+func (s *Server) getQemuConfig(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	_, vm, ok := s.lookupQemu(w, params)
+	if !ok {
+		return
+	}
+	if r.Form.Get("snapshot") != "" {
+		writeError(w, http.StatusNotImplemented, "proxmoxtest: snapshot configs are not supported")
+		return
+	}
+
+	data := map[string]string{}
+	for k, v := range vm.config {
+		data[k] = v
+	}
+	if r.Form.Get("current") != "1" {
+		for k := range vm.pendingDelete {
+			delete(data, k)
+		}
+		for k, v := range vm.pending {
+			data[k] = v
+		}
+	}
+	data["digest"] = vm.digest()
+	writeData(w, data)
+}
+
+// updateQemuConfig handles PUT and POST /nodes/{node}/qemu/{vmid}/config. Changes to a running VM become pending
+// unless they're in qemuHotOptions, and a digest that doesn't match fails the update like Proxmox.
+func (s *Server) updateQemuConfig(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	n, vm, ok := s.lookupQemu(w, params)
+	if !ok {
+		return
+	}
+	if digest := r.Form.Get("digest"); digest != "" && digest != vm.digest() {
+		writeError(w, http.StatusInternalServerError, "detected modified configuration - file changed by other user? Try again.")
+		return
+	}
+
+	running := vm.data.Status == "running"
+	for _, k := range splitList(r.PostForm.Get("revert")) {
+		delete(vm.pending, k)
+		delete(vm.pendingDelete, k)
+	}
+	for _, k := range splitList(r.PostForm.Get("delete")) {
+		if running && !qemuHotOptions[k] {
+			vm.pendingDelete[k] = true
+			continue
+		}
+		delete(vm.config, k)
+	}
+	for k := range r.PostForm {
+		if qemuUpdateParams[k] {
+			continue
+		}
+		if running && !qemuHotOptions[k] {
+			vm.pending[k] = r.PostForm.Get(k)
+			continue
+		}
+		vm.set(k, r.PostForm.Get(k))
+	}
+
+	if r.Method == http.MethodPost {
+		writeData(w, s.startTask(n.name, "qmconfig", params["vmid"], "root@pam", "OK"))
+		return
+	}
+	writeData(w, nil)
+}
+
+// getQemuPending handles GET /nodes/{node}/qemu/{vmid}/pending
+func (s *Server) getQemuPending(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	_, vm, ok := s.lookupQemu(w, params)
+	if !ok {
+		return
+	}
+
+	keys := map[string]bool{}
+	for k := range vm.config {
+		keys[k] = true
+	}
+	for k := range vm.pending {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	data := []map[string]interface{}{}
+	for _, k := range sorted {
+		item := map[string]interface{}{"key": k}
+		if v, ok := vm.config[k]; ok {
+			item["value"] = v
+		}
+		if v, ok := vm.pending[k]; ok {
+			item["pending"] = v
+		}
+		if vm.pendingDelete[k] {
+			item["delete"] = 1
+		}
+		data = append(data, item)
+	}
+	writeData(w, data)
+}
+
+// getNodeLxc handles GET /nodes/{node}/lxc
+func (s *Server) getNodeLxc(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	n, ok := s.lookupNode(w, params)
+	if !ok {
+		return
+	}
+	data := []proxmox.GetNodeLxcData{}
+	for _, id := range n.lxcIDs() {
+		data = append(data, n.lxc[id].data)
+	}
+	writeData(w, data)
+}
+
+// getQemuSnapshots handles GET /nodes/{node}/qemu/{vmid}/snapshot, including the "current" pseudo snapshot like Proxmox
+func (s *Server) getQemuSnapshots(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	_, vm, ok := s.lookupQemu(w, params)
+	if !ok {
+		return
+	}
+	data := append([]proxmox.GetQemuSnapshotsData{}, vm.snapshots...)
+	current := proxmox.GetQemuSnapshotsData{Name: "current", Description: "You are here!", Running: runningFlag(vm.data.Status)}
+	if len(vm.snapshots) > 0 {
+		parent := vm.snapshots[len(vm.snapshots)-1].Name
+		current.Parent = &parent
+	}
+	writeData(w, append(data, current))
+}
+
+// getLxcSnapshots handles GET /nodes/{node}/lxc/{vmid}/snapshot, including the "current" pseudo snapshot like Proxmox
+func (s *Server) getLxcSnapshots(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	_, ct, ok := s.lookupLxc(w, params)
+	if !ok {
+		return
+	}
+	data := append([]proxmox.GetLxcSnapshotsData{}, ct.snapshots...)
+	current := proxmox.GetLxcSnapshotsData{Name: "current", Description: "You are here!", Running: runningFlag(ct.data.Status)}
+	if len(ct.snapshots) > 0 {
+		parent := ct.snapshots[len(ct.snapshots)-1].Name
+		current.Parent = &parent
+	}
+	writeData(w, append(data, current))
+}
+
+// getQemuStatusCurrent handles GET /nodes/{node}/qemu/{vmid}/status/current
+func (s *Server) getQemuStatusCurrent(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	_, vm, ok := s.lookupQemu(w, params)
+	if !ok {
+		return
+	}
+	qmpStatus := vm.data.Status
+	if vm.paused {
+		qmpStatus = "paused"
+	}
+	name, cpus, maxMem, maxDisk, tags := vm.data.Name, vm.data.CPUs, int64(vm.data.MaxMem), int64(vm.data.MaxDisk), vm.data.Tags
+	data := proxmox.GetQemuStatusCurrentData{
+		Status:    vm.data.Status,
+		VMID:      vm.data.VMID,
+		HA:        proxmox.HAStatus{Managed: 0},
+		Name:      &name,
+		CPUs:      &cpus,
+		MaxMem:    &maxMem,
+		MaxDisk:   &maxDisk,
+		QMPStatus: &qmpStatus,
+		Tags:      &tags,
+	}
+	if vm.data.Status == "running" {
+		uptime := vm.data.Uptime
+		data.Uptime = &uptime
+	}
+	writeData(w, data)
+}
+
+// postQemuStatus handles POST /nodes/{node}/qemu/{vmid}/status/{action}, changing the status of the VM
+// and responding with the UPID of a finished task
+func (s *Server) postQemuStatus(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	n, vm, ok := s.lookupQemu(w, params)
+	if !ok {
+		return
+	}
+
+	vmid := params["vmid"]
+	running := vm.data.Status == "running"
+	switch params["action"] {
+	case "start":
+		if running {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("VM %s already running", vmid))
+			return
+		}
+		vm.data.Status = "running"
+		vm.applyPending()
+	case "stop", "shutdown":
+		if !running && params["action"] == "shutdown" {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("VM %s not running", vmid))
+			return
+		}
+		vm.data.Status = "stopped"
+		vm.paused = false
+	case "reboot", "reset":
+		if !running {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("VM %s not running", vmid))
+			return
+		}
+		vm.paused = false
+		if params["action"] == "reboot" {
+			vm.applyPending()
+		}
+	case "suspend":
+		if !running {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("VM %s not running", vmid))
+			return
+		}
+		if r.Form.Get("todisk") == "1" {
+			vm.data.Status = "stopped"
+		} else {
+			vm.paused = true
+		}
+	case "resume":
+		if !running {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("VM %s not running", vmid))
+			return
+		}
+		vm.paused = false
+	default:
+		writeError(w, http.StatusNotImplemented, fmt.Sprintf("Method 'POST /nodes/%s/qemu/%s/status/%s' not implemented", n.name, vmid, params["action"]))
+		return
+	}
+
+	writeData(w, s.startTask(n.name, "qm"+params["action"], vmid, "root@pam", "OK"))
+}
+
+// getLxcStatusCurrent handles GET /nodes/{node}/lxc/{vmid}/status/current
+func (s *Server) getLxcStatusCurrent(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	_, ct, ok := s.lookupLxc(w, params)
+	if !ok {
+		return
+	}
+	name, cpus, maxMem, maxDisk, maxSwap, tags, ctType := ct.data.Name, float64(ct.data.CPUs), int64(ct.data.MaxMem), int64(ct.data.MaxDisk), int64(ct.data.MaxSwap), ct.data.Tags, ct.data.Type
+	data := proxmox.GetLxcStatusCurrentData{
+		Status:  ct.data.Status,
+		VMID:    ct.data.VMID,
+		HA:      proxmox.HAStatus{Managed: 0},
+		Name:    &name,
+		CPUs:    &cpus,
+		MaxMem:  &maxMem,
+		MaxDisk: &maxDisk,
+		MaxSwap: &maxSwap,
+		Tags:    &tags,
+		Type:    &ctType,
+	}
+	if ct.data.Status == "running" {
+		uptime := ct.data.Uptime
+		data.Uptime = &uptime
+	}
+	writeData(w, data)
+}
+
+// postLxcStatus handles POST /nodes/{node}/lxc/{vmid}/status/{action}, changing the status of the container
+// and responding with the UPID of a finished task
+func (s *Server) postLxcStatus(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	n, ct, ok := s.lookupLxc(w, params)
+	if !ok {
+		return
+	}
+
+	vmid := params["vmid"]
+	running := ct.data.Status == "running"
+	switch params["action"] {
+	case "start":
+		if running {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("CT %s already running", vmid))
+			return
+		}
+		ct.data.Status = "running"
+	case "stop":
+		ct.data.Status = "stopped"
+	case "shutdown", "reboot", "suspend", "resume":
+		if !running {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("CT %s not running", vmid))
+			return
+		}
+		if params["action"] == "shutdown" {
+			ct.data.Status = "stopped"
+		}
+	default:
+		writeError(w, http.StatusNotImplemented, fmt.Sprintf("Method 'POST /nodes/%s/lxc/%s/status/%s' not implemented", n.name, vmid, params["action"]))
+		return
+	}
+
+	writeData(w, s.startTask(n.name, "vz"+params["action"], vmid, "root@pam", "OK"))
+}
+
+// lookupTask returns the task from the path parameters, or writes the error Proxmox responds with for unknown tasks
+func (s *Server) lookupTask(w http.ResponseWriter, params map[string]string) (*task, bool) {
+	if _, ok := s.lookupNode(w, params); !ok {
+		return nil, false
+	}
+	t, ok := s.tasks[params["upid"]]
+	if !ok || t.status.Node != params["node"] {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("unable to parse worker upid '%s'", params["upid"]))
+		return nil, false
+	}
+	return t, true
+}
+
+// getTaskStatus handles GET /nodes/{node}/tasks/{upid}/status
+func (s *Server) getTaskStatus(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+	if t, ok := s.lookupTask(w, params); ok {
+		writeData(w, t.status)
+	}
+}
+
+// getTaskLog handles GET /nodes/{node}/tasks/{upid}/log, with the optional start and limit parameters
+func (s *Server) getTaskLog(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	t, ok := s.lookupTask(w, params)
+	if !ok {
+		return
+	}
+
+	start, limit := 0, 50
+	if v := r.Form.Get("start"); v != "" {
+		start, _ = strconv.Atoi(v)
+	}
+	if v := r.Form.Get("limit"); v != "" {
+		limit, _ = strconv.Atoi(v)
+	}
+
+	// Line numbers start at 1 while start counts from 0, like Proxmox
+	lines := []proxmox.GetTaskLogData{}
+	for i := start; i < len(t.log) && len(lines) < limit; i++ {
+		lines = append(lines, proxmox.GetTaskLogData{N: i + 1, T: t.log[i]})
+	}
+
+	writeJSON(w, map[string]interface{}{"data": lines, "total": len(t.log)})
+}
+
+// splitList splits a comma separated list parameter, ignoring empty items
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// formInt returns an integer parameter of the request, or def if it isn't set
+func formInt(r *http.Request, name string, def int) int {
+	if v, err := strconv.Atoi(r.Form.Get(name)); err == nil {
+		return v
+	}
+	return def
+}
+
+// runningFlag returns the "running" flag Proxmox sets on the current pseudo snapshot
+func runningFlag(status string) *int {
+	running := 0
+	if status == "running" {
+		running = 1
+	}
+	return &running
+}
@@ -0,0 +1,225 @@
+// Package proxmoxtest provides an in-memory fake Proxmox VE API server for testing code that uses the go-proxmox client.
+//
+// The server keeps the state of a fake cluster, with nodes, QEMU and LXC guests, storage, snapshots and tasks,
+// which tests seed programmatically before pointing a real client at it:
+//
+//	s := proxmoxtest.NewServer()
+//	defer s.Close()
+//
+//	node := s.AddNode("srv1")
+//	node.AddQemu(proxmox.GetNodeQemuData{VMID: "100", Name: "web", Status: "running"})
+//
+//	c, _ := s.Client()
+//	vms, _, _ := c.Nodes.GetNodeQemu("srv1")
+package proxmoxtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	proxmox "github.com/starttoaster/go-proxmox"
+)
+
+const (
+	// DefaultTokenID is the ID of the API token the server accepts by default
+	DefaultTokenID = "root@pam!proxmoxtest"
+
+	// DefaultToken is the secret of the API token the server accepts by default
+	DefaultToken = "proxmoxtest-secret"
+
+	// apiPrefix is the path prefix of all API requests
+	apiPrefix = "/api2/json/"
+)
+
+// Server is a fake Proxmox VE API server backed by in-memory state. It's safe for concurrent use.
+type Server struct {
+	// URL is the base URL of the server, in the form "https://127.0.0.1:port"
+	URL string
+
+	server *httptest.Server
+	routes []route
+
+	mu      sync.Mutex
+	nodes   []*Node
+	tokens  map[string]string
+	users   map[string]string
+	tickets map[string]ticket
+	tasks   map[string]*task
+	seq     int
+}
+
+// NewServer starts a new fake Proxmox VE API server with an empty cluster. It accepts the API token DefaultTokenID.
+// The server should be closed with Close when it's no longer needed.
+func NewServer() *Server {
+	s := &Server{
+		tokens:  map[string]string{DefaultTokenID: DefaultToken},
+		users:   map[string]string{},
+		tickets: map[string]ticket{},
+		tasks:   map[string]*task{},
+	}
+	s.registerRoutes()
+	s.server = httptest.NewTLSServer(s)
+	s.URL = s.server.URL
+
+	return s
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// HTTPClient returns an HTTP client that trusts the server's self-signed certificate
+func (s *Server) HTTPClient() *http.Client {
+	return s.server.Client()
+}
+
+// Client returns a go-proxmox client pointed at the server, authenticated with the default API token.
+// Further options are applied after the defaults.
+func (s *Server) Client(options ...proxmox.ClientOptionFunc) (*proxmox.Client, error) {
+	opts := append([]proxmox.ClientOptionFunc{
+		proxmox.WithBaseURL(s.URL),
+		proxmox.WithHTTPClient(s.HTTPClient()),
+	}, options...)
+
+	return proxmox.NewClient(DefaultTokenID, DefaultToken, opts...)
+}
+
+// AddToken adds an API token the server accepts
+func (s *Server) AddToken(tokenID, secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[tokenID] = secret
+}
+
+// AddUser adds a user that can request tickets with a password. The username must include its realm, like "admin@pve".
+func (s *Server) AddUser(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[username] = password
+}
+
+// AddNode adds a node to the cluster and returns it for seeding guests, storage and tasks.
+// The node starts out online with a plausible status.
+func (s *Server) AddNode(name string) *Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := newNode(s, name)
+	s.nodes = append(s.nodes, n)
+	return n
+}
+
+// Node returns the node with the given name, or nil if it doesn't exist
+func (s *Server) Node(name string) *Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.node(name)
+}
+
+// node returns the node with the given name, or nil if it doesn't exist. The caller must hold s.mu.
+func (s *Server) node(name string) *Node {
+	for _, n := range s.nodes {
+		if n.name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// ServeHTTP implements the http.Handler interface for Server
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, apiPrefix) {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, apiPrefix), "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Tickets can be requested without authentication
+	if !(r.Method == http.MethodPost && path == "access/ticket") && !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "authentication failure")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for _, rt := range s.routes {
+		if params, ok := rt.match(r.Method, path); ok {
+			rt.handler(w, r, params)
+			return
+		}
+	}
+
+	// Proxmox responds to unknown API paths with 501
+	writeError(w, http.StatusNotImplemented, fmt.Sprintf("Method '%s /%s' not implemented", r.Method, path))
+}
+
+// route maps a request method and path pattern, like "nodes/{node}/qemu", to a handler
+type route struct {
+	method  string
+	pattern []string
+	handler func(w http.ResponseWriter, r *http.Request, params map[string]string)
+}
+
+// match reports whether the route matches a request, returning the values of the path parameters
+func (rt route) match(method, path string) (map[string]string, bool) {
+	segments := strings.Split(path, "/")
+	if method != rt.method || len(segments) != len(rt.pattern) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, p := range rt.pattern {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			params[strings.Trim(p, "{}")] = segments[i]
+			continue
+		}
+		if p != segments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// handle adds a route to the server
+func (s *Server) handle(method, pattern string, handler func(w http.ResponseWriter, r *http.Request, params map[string]string)) {
+	s.routes = append(s.routes, route{method: method, pattern: strings.Split(pattern, "/"), handler: handler})
+}
+
+// writeData writes a successful API response with the Proxmox data envelope
+func writeData(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, map[string]interface{}{"data": data})
+}
+
+// writeJSON writes a successful API response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error API response. Proxmox puts the message in the status line,
+// which Go's HTTP server can't customize, so the message is put in the body instead.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": nil, "message": msg + "\n"})
+}
+
+// writeParamError writes the response Proxmox sends for invalid request parameters
+func writeParamError(w http.ResponseWriter, param, msg string) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": nil, "errors": map[string]string{param: msg}})
+}
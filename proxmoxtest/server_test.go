@@ -0,0 +1,322 @@
+package proxmoxtest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	proxmox "github.com/starttoaster/go-proxmox"
+	"github.com/stretchr/testify/require"
+)
+
+func seed(s *Server) {
+	srv1 := s.AddNode("srv1")
+	srv1.AddQemu(proxmox.GetNodeQemuData{VMID: "100", Name: "web", Status: "running", CPUs: 2, MaxMem: 2 << 30})
+	srv1.AddQemu(proxmox.GetNodeQemuData{VMID: "101", Name: "db"})
+	srv1.AddQemuSnapshot(100, proxmox.GetQemuSnapshotsData{Name: "before-upgrade"})
+	srv1.AddLxc(proxmox.GetNodeLxcData{VMID: "200", Name: "dns", Status: "running"})
+	srv1.AddStorage(proxmox.GetNodeStorageData{Storage: "local-lvm", Type: "lvmthin", Content: "images,rootdir", Active: 1, Enabled: 1})
+
+	s.AddNode("srv2").SetIP("127.0.0.2")
+}
+
+func TestServerNodes(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	seed(s)
+
+	c, err := s.Client()
+	require.NoError(t, err)
+
+	nodes, _, err := c.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Len(t, nodes.Data, 2)
+	require.Equal(t, "srv1", nodes.Data[0].Node)
+	require.Equal(t, "online", nodes.Data[0].Status)
+
+	status, _, err := c.Nodes.GetNodeStatus("srv2")
+	require.NoError(t, err)
+	require.Equal(t, "pve-manager/8.2.4/faa83925c9641325", status.Data.PveVersion)
+
+	version, _, err := c.Nodes.GetNodeVersion("srv1")
+	require.NoError(t, err)
+	require.Equal(t, "8.2.4", version.Data.Version)
+
+	storage, _, err := c.Nodes.GetNodeStorage("srv1")
+	require.NoError(t, err)
+	require.Len(t, storage.Data, 1)
+	require.Equal(t, "local-lvm", storage.Data[0].Storage)
+
+	_, _, err = c.Nodes.GetNodeStatus("srv3")
+	require.Error(t, err)
+}
+
+func TestServerGuests(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	seed(s)
+
+	c, err := s.Client()
+	require.NoError(t, err)
+
+	vms, _, err := c.Nodes.GetNodeQemu("srv1")
+	require.NoError(t, err)
+	require.Len(t, vms.Data, 2)
+	require.Equal(t, proxmox.IntOrString("100"), vms.Data[0].VMID)
+	require.Equal(t, "running", vms.Data[0].Status)
+	require.Equal(t, "stopped", vms.Data[1].Status)
+
+	cts, _, err := c.Nodes.GetNodeLxc("srv1")
+	require.NoError(t, err)
+	require.Len(t, cts.Data, 1)
+	require.Equal(t, "lxc", cts.Data[0].Type)
+
+	snapshots, _, err := c.Nodes.GetQemuSnapshots("srv1", 100)
+	require.NoError(t, err)
+	require.Len(t, snapshots.Data, 2)
+	require.Equal(t, "before-upgrade", snapshots.Data[0].Name)
+	require.Equal(t, "current", snapshots.Data[1].Name)
+	require.Equal(t, "before-upgrade", *snapshots.Data[1].Parent)
+	require.Equal(t, 1, *snapshots.Data[1].Running)
+
+	_, _, err = c.Nodes.GetLxcSnapshots("srv1", 999)
+	require.True(t, proxmox.IsNotFound(err))
+}
+
+func TestServerQemuStatus(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	seed(s)
+
+	c, err := s.Client()
+	require.NoError(t, err)
+
+	task, _, err := c.Nodes.StartQemu("srv1", 101, nil)
+	require.NoError(t, err)
+	status, err := c.Tasks.Wait(task.Data, nil)
+	require.NoError(t, err)
+	require.Equal(t, "qmstart", status.Type)
+
+	_, _, err = c.Nodes.StartQemu("srv1", 101, nil)
+	require.Error(t, err)
+
+	_, _, err = c.Nodes.SuspendQemu("srv1", 101, nil)
+	require.NoError(t, err)
+	current, _, err := c.Nodes.GetQemuStatusCurrent("srv1", 101)
+	require.NoError(t, err)
+	require.Equal(t, "running", current.Data.Status)
+	require.Equal(t, "paused", *current.Data.QMPStatus)
+
+	_, _, err = c.Nodes.ShutdownQemu("srv1", 101, &proxmox.ShutdownQemuOptions{ForceStop: true})
+	require.NoError(t, err)
+	current, _, err = c.Nodes.GetQemuStatusCurrent("srv1", 101)
+	require.NoError(t, err)
+	require.Equal(t, "stopped", current.Data.Status)
+}
+
+func TestServerCreateQemu(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	seed(s)
+
+	c, err := s.Client()
+	require.NoError(t, err)
+
+	task, _, err := c.Nodes.CreateQemu("srv2", &proxmox.QemuConfig{VMID: 110, Name: "web-01", Cores: 2, Memory: 2048, Start: true})
+	require.NoError(t, err)
+	status, err := c.Tasks.Wait(task.Data, nil)
+	require.NoError(t, err)
+	require.Equal(t, "qmcreate", status.Type)
+
+	vms, _, err := c.Nodes.GetNodeQemu("srv2")
+	require.NoError(t, err)
+	require.Len(t, vms.Data, 1)
+	require.Equal(t, "web-01", vms.Data[0].Name)
+	require.Equal(t, "running", vms.Data[0].Status)
+	require.Equal(t, 2048<<20, vms.Data[0].MaxMem)
+
+	_, _, err = c.Nodes.CreateQemu("srv1", &proxmox.QemuConfig{VMID: 110})
+	require.Error(t, err)
+}
+
+func TestServerQemuConfig(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	seed(s)
+
+	c, err := s.Client()
+	require.NoError(t, err)
+
+	config, _, err := c.Nodes.GetQemuConfig("srv1", 100, nil)
+	require.NoError(t, err)
+	require.Equal(t, "web", config.Data["name"])
+	require.Equal(t, "2048", config.Data["memory"])
+	digest := config.Data.Digest()
+	require.NotEmpty(t, digest)
+
+	// Hardware changes to a running VM become pending, the name is changed right away
+	_, err = c.Nodes.UpdateQemuConfig("srv1", 100, &proxmox.UpdateQemuConfigOptions{
+		Values: proxmox.IndexedOptions{"memory": "4096", "cores": "4", "name": "web-01"},
+		Digest: digest,
+	})
+	require.NoError(t, err)
+
+	pending, _, err := c.Nodes.GetQemuPending("srv1", 100)
+	require.NoError(t, err)
+	byKey := map[string]proxmox.GetQemuPendingData{}
+	for _, p := range pending.Data {
+		byKey[p.Key] = p
+	}
+	require.Equal(t, "2048", *byKey["memory"].Value)
+	require.Equal(t, "4096", *byKey["memory"].Pending)
+	require.Nil(t, byKey["name"].Pending)
+
+	current, _, err := c.Nodes.GetQemuConfig("srv1", 100, &proxmox.GetQemuConfigOptions{Current: true})
+	require.NoError(t, err)
+	require.Equal(t, "2048", current.Data["memory"])
+	require.Equal(t, "web-01", current.Data["name"])
+
+	// The digest changed with the update, so an update with the old digest conflicts
+	_, err = c.Nodes.UpdateQemuConfig("srv1", 100, &proxmox.UpdateQemuConfigOptions{Values: proxmox.IndexedOptions{"memory": "8192"}, Digest: digest})
+	require.True(t, proxmox.IsConfigConflict(err))
+
+	_, err = c.Nodes.RevertQemuPending("srv1", 100, "cores")
+	require.NoError(t, err)
+
+	// Rebooting applies the remaining pending changes
+	task, _, err := c.Nodes.RebootQemu("srv1", 100, nil)
+	require.NoError(t, err)
+	_, err = c.Tasks.Wait(task.Data, nil)
+	require.NoError(t, err)
+
+	config, _, err = c.Nodes.GetQemuConfig("srv1", 100, &proxmox.GetQemuConfigOptions{Current: true})
+	require.NoError(t, err)
+	require.Equal(t, "4096", config.Data["memory"])
+	require.Equal(t, "2", config.Data["cores"])
+
+	vms, _, err := c.Nodes.GetNodeQemu("srv1")
+	require.NoError(t, err)
+	require.Equal(t, "web-01", vms.Data[0].Name)
+	require.Equal(t, 4096<<20, vms.Data[0].MaxMem)
+
+	// Stopped VMs are changed right away
+	task, _, err = c.Nodes.UpdateQemuConfigAsync("srv1", 101, &proxmox.UpdateQemuConfigOptions{Values: proxmox.IndexedOptions{"cores": "8"}})
+	require.NoError(t, err)
+	_, err = c.Tasks.Wait(task.Data, nil)
+	require.NoError(t, err)
+	config, _, err = c.Nodes.GetQemuConfig("srv1", 101, &proxmox.GetQemuConfigOptions{Current: true})
+	require.NoError(t, err)
+	require.Equal(t, "8", config.Data["cores"])
+}
+
+func TestServerLxcStatus(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	seed(s)
+
+	c, err := s.Client()
+	require.NoError(t, err)
+
+	task, _, err := c.Nodes.ShutdownLxc("srv1", 200, &proxmox.ShutdownLxcOptions{Timeout: 30})
+	require.NoError(t, err)
+	status, err := c.Tasks.Wait(task.Data, nil)
+	require.NoError(t, err)
+	require.Equal(t, "vzshutdown", status.Type)
+
+	current, _, err := c.Nodes.GetLxcStatusCurrent("srv1", 200)
+	require.NoError(t, err)
+	require.Equal(t, "stopped", current.Data.Status)
+	require.Equal(t, "dns", *current.Data.Name)
+
+	_, _, err = c.Nodes.RebootLxc("srv1", 200, nil)
+	require.Error(t, err)
+
+	_, _, err = c.Nodes.StartLxc("srv1", 200, nil)
+	require.NoError(t, err)
+	current, _, err = c.Nodes.GetLxcStatusCurrent("srv1", 200)
+	require.NoError(t, err)
+	require.Equal(t, "running", current.Data.Status)
+}
+
+func TestServerCluster(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	seed(s)
+
+	c, err := s.Client()
+	require.NoError(t, err)
+
+	status, _, err := c.Cluster.GetClusterStatus()
+	require.NoError(t, err)
+	require.Len(t, status.Data, 3)
+	require.Equal(t, "cluster", status.Data[0].Type)
+	require.Equal(t, "127.0.0.2", *status.Data[2].IP)
+
+	resources, _, err := c.Cluster.GetClusterResources()
+	require.NoError(t, err)
+
+	var ids []string
+	for _, r := range resources.Data {
+		ids = append(ids, r.ID)
+	}
+	require.Equal(t, []string{"node/srv1", "qemu/100", "qemu/101", "lxc/200", "storage/srv1/local-lvm", "node/srv2"}, ids)
+}
+
+func TestServerTasks(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	seed(s)
+
+	c, err := s.Client()
+	require.NoError(t, err)
+
+	failed := s.Node("srv1").AddTask("vzdump", "100", "job errors", "INFO: starting backup")
+	_, err = c.Tasks.Wait(failed, nil)
+	require.Error(t, err)
+	require.Equal(t, []string{"INFO: starting backup", "TASK ERROR: job errors"}, err.(*proxmox.TaskError).Log)
+
+	running := s.Node("srv1").AddTask("qmstart", "101", "")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		s.FinishTask(running, "OK")
+	}()
+	status, err := c.Tasks.WaitWithContext(context.Background(), running, &proxmox.WaitOptions{PollInterval: 5 * time.Millisecond})
+	require.NoError(t, err)
+	require.True(t, status.IsSuccessful())
+
+	require.Len(t, s.Tasks(), 2)
+	require.False(t, s.FinishTask(running, "OK"))
+}
+
+func TestServerAuthentication(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	seed(s)
+	s.AddUser("admin@pve", "password")
+
+	c, err := proxmox.NewClient(DefaultTokenID, "wrong", proxmox.WithBaseURL(s.URL), proxmox.WithHTTPClient(s.HTTPClient()))
+	require.NoError(t, err)
+	_, _, err = c.Nodes.GetNodes()
+	require.True(t, proxmox.IsPermissionDenied(err))
+
+	c, err = proxmox.NewClientWithPassword("admin@pve", "password", proxmox.WithBaseURL(s.URL), proxmox.WithHTTPClient(s.HTTPClient()))
+	require.NoError(t, err)
+	nodes, _, err := c.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Len(t, nodes.Data, 2)
+}
+
+func TestServerNotImplemented(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	c, err := s.Client()
+	require.NoError(t, err)
+
+	req, err := c.NewRequest(http.MethodGet, "pools", nil)
+	require.NoError(t, err)
+	resp, err := c.Do(req, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+}
@@ -0,0 +1,90 @@
+package proxmoxtest
+
+import (
+	"sort"
+	"time"
+
+	proxmox "github.com/starttoaster/go-proxmox"
+)
+
+// task is a task the fake cluster ran or is running
+type task struct {
+	status proxmox.GetTaskStatusData
+	log    []string
+}
+
+// startTask adds a task and returns its UPID. If exitStatus is empty the task keeps running. The caller must hold s.mu.
+func (s *Server) startTask(node, taskType, id, user, exitStatus string, log ...string) string {
+	s.seq++
+	upid := &proxmox.UPID{
+		Node:      node,
+		PID:       1000 + s.seq,
+		PStart:    100000 + s.seq,
+		StartTime: time.Now(),
+		Type:      taskType,
+		ID:        id,
+		User:      user,
+	}
+
+	t := &task{
+		status: proxmox.GetTaskStatusData{
+			ID:        id,
+			Node:      node,
+			PID:       upid.PID,
+			PStart:    upid.PStart,
+			StartTime: int(upid.StartTime.Unix()),
+			Status:    "running",
+			Type:      taskType,
+			UPID:      upid.String(),
+			User:      user,
+		},
+		log: log,
+	}
+	s.tasks[t.status.UPID] = t
+
+	if exitStatus != "" {
+		s.finishTask(t, exitStatus)
+	}
+
+	return t.status.UPID
+}
+
+// finishTask stops a task with the given exit status. The caller must hold s.mu.
+func (s *Server) finishTask(t *task, exitStatus string) {
+	t.status.Status = "stopped"
+	t.status.ExitStatus = &exitStatus
+	if exitStatus == "OK" {
+		t.log = append(t.log, "TASK OK")
+	} else {
+		t.log = append(t.log, "TASK ERROR: "+exitStatus)
+	}
+}
+
+// FinishTask stops a running task with the given exit status, which is "OK" for successful tasks
+// and the error message otherwise. It reports whether a running task with the UPID existed.
+func (s *Server) FinishTask(upid, exitStatus string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tasks[upid]
+	if !ok || t.status.Status != "running" {
+		return false
+	}
+	s.finishTask(t, exitStatus)
+	return true
+}
+
+// Tasks returns the status of every task in the order they started
+func (s *Server) Tasks() []proxmox.GetTaskStatusData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := make([]proxmox.GetTaskStatusData, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		tasks = append(tasks, t.status)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].PID < tasks[j].PID
+	})
+	return tasks
+}
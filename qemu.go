@@ -0,0 +1,789 @@
+package proxmox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-querystring/query"
+)
+
+// GetQemuStatusCurrentResponse contains the response for the /nodes/{node}/qemu/{vmid}/status/current endpoint
+type GetQemuStatusCurrentResponse struct {
+	Data GetQemuStatusCurrentData `json:"data"`
+}
+
+// GetQemuStatusCurrentData contains the current status of a VM from a GetQemuStatusCurrent response
+type GetQemuStatusCurrentData struct {
+	Status         string                            `json:"status"` // Either "running" or "stopped"
+	VMID           IntOrString                       `json:"vmid"`
+	HA             QemuHAStatus                      `json:"ha"`
+	Agent          *int                              `json:"agent,omitempty"` // QEMU guest agent is enabled
+	Balloon        *int64                            `json:"balloon,omitempty"`
+	BalloonInfo    *QemuBalloonInfo                  `json:"ballooninfo,omitempty"` // Only set for running VMs with a balloon device
+	BlockStat      map[string]map[string]interface{} `json:"blockstat,omitempty"`
+	Clipboard      *string                           `json:"clipboard,omitempty"`
+	CPU            *float64                          `json:"cpu,omitempty"`
+	CPUs           *int                              `json:"cpus,omitempty"`
+	Disk           *int64                            `json:"disk,omitempty"`
+	DiskRead       *int64                            `json:"diskread,omitempty"`
+	DiskWrite      *int64                            `json:"diskwrite,omitempty"`
+	FreeMem        *int64                            `json:"freemem,omitempty"` // Reported by the guest agent or balloon driver
+	Lock           *string                           `json:"lock,omitempty"`
+	MaxDisk        *int64                            `json:"maxdisk,omitempty"`
+	MaxMem         *int64                            `json:"maxmem,omitempty"`
+	Mem            *int64                            `json:"mem,omitempty"`
+	Name           *string                           `json:"name,omitempty"`
+	NetIn          *int64                            `json:"netin,omitempty"`
+	NetOut         *int64                            `json:"netout,omitempty"`
+	NICs           map[string]QemuNICStat            `json:"nics,omitempty"`
+	PID            *int                              `json:"pid,omitempty"`
+	ProxmoxSupport map[string]interface{}            `json:"proxmox-support,omitempty"`
+	QMPStatus      *string                           `json:"qmpstatus,omitempty"`       // Status reported by QEMU, like "running", "paused" or "prelaunch"
+	RunningMachine *string                           `json:"running-machine,omitempty"` // Machine type QEMU runs with, like "pc-i440fx-8.1+pve0"
+	RunningQemu    *string                           `json:"running-qemu,omitempty"`    // QEMU version the VM runs with
+	Spice          *int                              `json:"spice,omitempty"`
+	Tags           *string                           `json:"tags,omitempty"`
+	Template       *int                              `json:"template,omitempty"`
+	Uptime         *int                              `json:"uptime,omitempty"`
+}
+
+// QemuHAStatus is the high availability status of a VM. It's the HAStatus VMs and containers share.
+type QemuHAStatus = HAStatus
+
+// QemuBalloonInfo is the memory balloon status of a running VM
+type QemuBalloonInfo struct {
+	Actual          int64  `json:"actual"`
+	MaxMem          int64  `json:"max_mem"`
+	TotalMem        *int64 `json:"total_mem,omitempty"`
+	FreeMem         *int64 `json:"free_mem,omitempty"`
+	MemSwappedIn    *int64 `json:"mem_swapped_in,omitempty"`
+	MemSwappedOut   *int64 `json:"mem_swapped_out,omitempty"`
+	MajorPageFaults *int64 `json:"major_page_faults,omitempty"`
+	MinorPageFaults *int64 `json:"minor_page_faults,omitempty"`
+	LastUpdate      *int64 `json:"last_update,omitempty"`
+}
+
+// QemuNICStat is the traffic of a network device of a running VM
+type QemuNICStat struct {
+	NetIn  int64 `json:"netin"`
+	NetOut int64 `json:"netout"`
+}
+
+// GetQemuStatusCurrent makes a GET request to the /nodes/{node}/qemu/{vmid}/status/current endpoint
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu/{vmid}/status/current
+func (s *NodeService) GetQemuStatusCurrent(nodeName string, vmID int) (*GetQemuStatusCurrentResponse, *http.Response, error) {
+	return s.GetQemuStatusCurrentWithContext(context.Background(), nodeName, vmID)
+}
+
+// GetQemuStatusCurrentWithContext is like GetQemuStatusCurrent but uses the given context for the request
+func (s *NodeService) GetQemuStatusCurrentWithContext(ctx context.Context, nodeName string, vmID int) (*GetQemuStatusCurrentResponse, *http.Response, error) {
+	u := fmt.Sprintf("nodes/%s/qemu/%d/status/current", nodeName, vmID)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.GetQemuStatusCurrent"), http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := new(GetQemuStatusCurrentResponse)
+	resp, err := s.client.Do(req, d)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return d, resp, nil
+}
+
+// StartQemuOptions contains the optional parameters for the /nodes/{node}/qemu/{vmid}/status/start endpoint
+type StartQemuOptions struct {
+	ForceCPU         string `url:"force-cpu,omitempty"`         // Override the CPU type of the VM, used for migrations
+	Machine          string `url:"machine,omitempty"`           // Machine type to start the VM with
+	MigratedFrom     string `url:"migratedfrom,omitempty"`      // Cluster node the VM is migrated from
+	MigrationNetwork string `url:"migration_network,omitempty"` // CIDR of the network used for migration
+	MigrationType    string `url:"migration_type,omitempty"`    // Either "secure" or "insecure"
+	SkipLock         bool   `url:"skiplock,omitempty,int"`      // Ignore locks, only root is allowed to use this option
+	StateURI         string `url:"stateuri,omitempty"`          // Location to restore the VM state from
+	TargetStorage    string `url:"targetstorage,omitempty"`     // Mapping from source to target storages for migrations
+	Timeout          int    `url:"timeout,omitempty"`           // Wait at most this many seconds
+}
+
+// StartQemu makes a POST request to the /nodes/{node}/qemu/{vmid}/status/start endpoint
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu/{vmid}/status/start
+func (s *NodeService) StartQemu(nodeName string, vmID int, opt *StartQemuOptions) (*TaskResponse, *http.Response, error) {
+	return s.StartQemuWithContext(context.Background(), nodeName, vmID, opt)
+}
+
+// StartQemuWithContext is like StartQemu but uses the given context for the request
+func (s *NodeService) StartQemuWithContext(ctx context.Context, nodeName string, vmID int, opt *StartQemuOptions) (*TaskResponse, *http.Response, error) {
+	return s.qemuStatus(withOperation(ctx, "NodeService.StartQemu"), nodeName, vmID, "start", opt)
+}
+
+// StopQemuOptions contains the optional parameters for the /nodes/{node}/qemu/{vmid}/status/stop endpoint
+type StopQemuOptions struct {
+	KeepActive       bool   `url:"keepActive,omitempty,int"`        // Do not deactivate storage volumes
+	MigratedFrom     string `url:"migratedfrom,omitempty"`          // Cluster node the VM is migrated from
+	OverruleShutdown bool   `url:"overrule-shutdown,omitempty,int"` // Abort active shutdown tasks of the VM first, requires Proxmox VE 8.1
+	SkipLock         bool   `url:"skiplock,omitempty,int"`          // Ignore locks, only root is allowed to use this option
+	Timeout          int    `url:"timeout,omitempty"`               // Wait at most this many seconds
+}
+
+// StopQemu makes a POST request to the /nodes/{node}/qemu/{vmid}/status/stop endpoint
+// It stops the VM immediately, like pulling the power plug. Use ShutdownQemu to shut it down gracefully.
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu/{vmid}/status/stop
+func (s *NodeService) StopQemu(nodeName string, vmID int, opt *StopQemuOptions) (*TaskResponse, *http.Response, error) {
+	return s.StopQemuWithContext(context.Background(), nodeName, vmID, opt)
+}
+
+// StopQemuWithContext is like StopQemu but uses the given context for the request
+func (s *NodeService) StopQemuWithContext(ctx context.Context, nodeName string, vmID int, opt *StopQemuOptions) (*TaskResponse, *http.Response, error) {
+	if opt != nil && opt.OverruleShutdown {
+		if err := s.client.RequireVersion(ctx, Version{Major: 8, Minor: 1}, "overrule-shutdown"); err != nil {
+			return nil, nil, err
+		}
+	}
+	return s.qemuStatus(withOperation(ctx, "NodeService.StopQemu"), nodeName, vmID, "stop", opt)
+}
+
+// ShutdownQemuOptions contains the optional parameters for the /nodes/{node}/qemu/{vmid}/status/shutdown endpoint
+type ShutdownQemuOptions struct {
+	ForceStop  bool `url:"forceStop,omitempty,int"`  // Stop the VM if it didn't shut down before the timeout
+	KeepActive bool `url:"keepActive,omitempty,int"` // Do not deactivate storage volumes
+	SkipLock   bool `url:"skiplock,omitempty,int"`   // Ignore locks, only root is allowed to use this option
+	Timeout    int  `url:"timeout,omitempty"`        // Wait at most this many seconds
+}
+
+// ShutdownQemu makes a POST request to the /nodes/{node}/qemu/{vmid}/status/shutdown endpoint
+// It sends an ACPI shutdown event, or asks the QEMU guest agent to shut down the VM if it's enabled.
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu/{vmid}/status/shutdown
+func (s *NodeService) ShutdownQemu(nodeName string, vmID int, opt *ShutdownQemuOptions) (*TaskResponse, *http.Response, error) {
+	return s.ShutdownQemuWithContext(context.Background(), nodeName, vmID, opt)
+}
+
+// ShutdownQemuWithContext is like ShutdownQemu but uses the given context for the request
+func (s *NodeService) ShutdownQemuWithContext(ctx context.Context, nodeName string, vmID int, opt *ShutdownQemuOptions) (*TaskResponse, *http.Response, error) {
+	return s.qemuStatus(withOperation(ctx, "NodeService.ShutdownQemu"), nodeName, vmID, "shutdown", opt)
+}
+
+// RebootQemuOptions contains the optional parameters for the /nodes/{node}/qemu/{vmid}/status/reboot endpoint
+type RebootQemuOptions struct {
+	Timeout int `url:"timeout,omitempty"` // Wait at most this many seconds for the shutdown
+}
+
+// RebootQemu makes a POST request to the /nodes/{node}/qemu/{vmid}/status/reboot endpoint
+// It shuts down the VM and starts it again, applying pending changes.
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu/{vmid}/status/reboot
+func (s *NodeService) RebootQemu(nodeName string, vmID int, opt *RebootQemuOptions) (*TaskResponse, *http.Response, error) {
+	return s.RebootQemuWithContext(context.Background(), nodeName, vmID, opt)
+}
+
+// RebootQemuWithContext is like RebootQemu but uses the given context for the request
+func (s *NodeService) RebootQemuWithContext(ctx context.Context, nodeName string, vmID int, opt *RebootQemuOptions) (*TaskResponse, *http.Response, error) {
+	return s.qemuStatus(withOperation(ctx, "NodeService.RebootQemu"), nodeName, vmID, "reboot", opt)
+}
+
+// ResetQemuOptions contains the optional parameters for the /nodes/{node}/qemu/{vmid}/status/reset endpoint
+type ResetQemuOptions struct {
+	SkipLock bool `url:"skiplock,omitempty,int"` // Ignore locks, only root is allowed to use this option
+}
+
+// ResetQemu makes a POST request to the /nodes/{node}/qemu/{vmid}/status/reset endpoint
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu/{vmid}/status/reset
+func (s *NodeService) ResetQemu(nodeName string, vmID int, opt *ResetQemuOptions) (*TaskResponse, *http.Response, error) {
+	return s.ResetQemuWithContext(context.Background(), nodeName, vmID, opt)
+}
+
+// ResetQemuWithContext is like ResetQemu but uses the given context for the request
+func (s *NodeService) ResetQemuWithContext(ctx context.Context, nodeName string, vmID int, opt *ResetQemuOptions) (*TaskResponse, *http.Response, error) {
+	return s.qemuStatus(withOperation(ctx, "NodeService.ResetQemu"), nodeName, vmID, "reset", opt)
+}
+
+// SuspendQemuOptions contains the optional parameters for the /nodes/{node}/qemu/{vmid}/status/suspend endpoint
+type SuspendQemuOptions struct {
+	SkipLock     bool   `url:"skiplock,omitempty,int"` // Ignore locks, only root is allowed to use this option
+	StateStorage string `url:"statestorage,omitempty"` // Storage the VM state is saved to when suspending to disk
+	ToDisk       bool   `url:"todisk,omitempty,int"`   // Suspend to disk, the VM is resumed by starting it
+}
+
+// SuspendQemu makes a POST request to the /nodes/{node}/qemu/{vmid}/status/suspend endpoint
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu/{vmid}/status/suspend
+func (s *NodeService) SuspendQemu(nodeName string, vmID int, opt *SuspendQemuOptions) (*TaskResponse, *http.Response, error) {
+	return s.SuspendQemuWithContext(context.Background(), nodeName, vmID, opt)
+}
+
+// SuspendQemuWithContext is like SuspendQemu but uses the given context for the request
+func (s *NodeService) SuspendQemuWithContext(ctx context.Context, nodeName string, vmID int, opt *SuspendQemuOptions) (*TaskResponse, *http.Response, error) {
+	return s.qemuStatus(withOperation(ctx, "NodeService.SuspendQemu"), nodeName, vmID, "suspend", opt)
+}
+
+// ResumeQemuOptions contains the optional parameters for the /nodes/{node}/qemu/{vmid}/status/resume endpoint
+type ResumeQemuOptions struct {
+	NoCheck  bool `url:"nocheck,omitempty,int"`  // Used by migrations
+	SkipLock bool `url:"skiplock,omitempty,int"` // Ignore locks, only root is allowed to use this option
+}
+
+// ResumeQemu makes a POST request to the /nodes/{node}/qemu/{vmid}/status/resume endpoint
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu/{vmid}/status/resume
+func (s *NodeService) ResumeQemu(nodeName string, vmID int, opt *ResumeQemuOptions) (*TaskResponse, *http.Response, error) {
+	return s.ResumeQemuWithContext(context.Background(), nodeName, vmID, opt)
+}
+
+// ResumeQemuWithContext is like ResumeQemu but uses the given context for the request
+func (s *NodeService) ResumeQemuWithContext(ctx context.Context, nodeName string, vmID int, opt *ResumeQemuOptions) (*TaskResponse, *http.Response, error) {
+	return s.qemuStatus(withOperation(ctx, "NodeService.ResumeQemu"), nodeName, vmID, "resume", opt)
+}
+
+// qemuStatus makes a POST request to a /nodes/{node}/qemu/{vmid}/status/{action} endpoint that starts a task
+func (s *NodeService) qemuStatus(ctx context.Context, nodeName string, vmID int, action string, opt interface{}) (*TaskResponse, *http.Response, error) {
+	u := fmt.Sprintf("nodes/%s/qemu/%d/status/%s", nodeName, vmID, action)
+	req, err := s.client.NewRequestWithContext(ctx, http.MethodPost, u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := new(TaskResponse)
+	resp, err := s.client.Do(req, d)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return d, resp, nil
+}
+
+// ErrInvalidConfig is wrapped by the errors returned for guest configurations that fail validation before being sent
+var ErrInvalidConfig = errors.New("invalid guest configuration")
+
+// guestName matches the DNS names Proxmox accepts as guest names
+var guestName = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9.-]*[a-zA-Z0-9])?$`)
+
+// cloudInitVolume matches the names of cloud-init drive volumes Proxmox creates, like vm-100-cloudinit on LVM storage
+// or 100/vm-100-cloudinit.qcow2 on directory storage
+var cloudInitVolume = regexp.MustCompile(`^(\d+/)?vm-\d+-cloudinit(\.[a-z0-9]+)?$`)
+
+// qemuIndexedKeys are the prefixes of numbered config keys and the highest number Proxmox accepts for them
+var qemuIndexedKeys = map[string]int{
+	"ide":      3,
+	"sata":     5,
+	"scsi":     30,
+	"virtio":   15,
+	"net":      31,
+	"ipconfig": 31,
+}
+
+// IndexedOptions are options of a guest configuration keyed by their name, usually numbered ones like "scsi0", "net0" or "ipconfig0".
+// The values are property strings, like "local-lvm:32,discard=on" or "virtio,bridge=vmbr0,firewall=1".
+type IndexedOptions map[string]string
+
+// EncodeValues implements the query.Encoder interface for IndexedOptions, encoding every option as its own parameter
+func (o IndexedOptions) EncodeValues(_ string, v *url.Values) error {
+	for key, value := range o {
+		v.Set(key, value)
+	}
+	return nil
+}
+
+// QemuConfig contains the configuration of a new QEMU virtual machine for CreateQemu.
+// Only VMID is required, Proxmox applies its defaults to everything else.
+type QemuConfig struct {
+	VMID        int    `url:"vmid"`                  // Unique ID of the VM, at least 100
+	Name        string `url:"name,omitempty"`        // DNS name of the VM
+	Description string `url:"description,omitempty"` // Notes shown in the web UI
+	Tags        string `url:"tags,omitempty"`        // Semicolon separated tags
+	Pool        string `url:"pool,omitempty"`        // Resource pool to add the VM to
+	OSType      string `url:"ostype,omitempty"`      // Guest operating system, like "l26" or "win11"
+
+	// Hardware
+	Machine string `url:"machine,omitempty"` // Machine type, like "q35" or "pc-i440fx-8.1"
+	BIOS    string `url:"bios,omitempty"`    // Either "seabios" or "ovmf" for UEFI
+	CPU     string `url:"cpu,omitempty"`     // Emulated CPU type, like "host" or "x86-64-v2-AES"
+	Cores   int    `url:"cores,omitempty"`   // Cores per socket
+	Sockets int    `url:"sockets,omitempty"` // Number of CPU sockets
+	Memory  int    `url:"memory,omitempty"`  // Memory in MiB
+	Balloon *int   `url:"balloon,omitempty"` // Minimum memory in MiB with ballooning, 0 disables the balloon device
+	SCSIHW  string `url:"scsihw,omitempty"`  // SCSI controller, like "virtio-scsi-single"
+	VGA     string `url:"vga,omitempty"`     // Display, like "std" or "serial0"
+	Agent   string `url:"agent,omitempty"`   // QEMU guest agent, like "1" or "enabled=1,fstrim_cloned_disks=1"
+
+	// Disks are keyed by ideN, sataN, scsiN or virtioN, for example "scsi0": "local-lvm:32,discard=on,iothread=1"
+	// to allocate a new 32 GiB disk, or "ide2": "local-lvm:cloudinit" for a cloud-init drive
+	Disks IndexedOptions `url:"disks,omitempty"`
+
+	// NICs are keyed by netN, for example "net0": "virtio,bridge=vmbr0,firewall=1"
+	NICs IndexedOptions `url:"nics,omitempty"`
+
+	// EFIDisk is the disk storing UEFI variables when BIOS is "ovmf", like "local-lvm:1,efitype=4m,pre-enrolled-keys=1"
+	EFIDisk string `url:"efidisk0,omitempty"`
+
+	// TPMState is the disk storing the state of a TPM, like "local-lvm:1,version=v2.0"
+	TPMState string `url:"tpmstate0,omitempty"`
+
+	// Boot is the boot order, like "order=scsi0;net0"
+	Boot   string `url:"boot,omitempty"`
+	OnBoot bool   `url:"onboot,omitempty,int"` // Start the VM when the node boots
+	Start  bool   `url:"start,omitempty,int"`  // Start the VM once it was created
+
+	// Cloud-init, which requires a cloud-init drive in Disks
+	CIType       string `url:"citype,omitempty"`        // Either "nocloud", "configdrive2" or "opennebula"
+	CIUser       string `url:"ciuser,omitempty"`        // User to create instead of the image's default user
+	CIPassword   string `url:"cipassword,omitempty"`    // Password of the user, prefer SSHKeys
+	CIUpgrade    *bool  `url:"ciupgrade,omitempty,int"` // Upgrade packages on first boot, Proxmox defaults to true
+	SSHKeys      string `url:"-"`                       // Public SSH keys in OpenSSH format, one per line
+	Nameserver   string `url:"nameserver,omitempty"`    // DNS servers, defaults to the node's
+	SearchDomain string `url:"searchdomain,omitempty"`  // DNS search domain, defaults to the node's
+
+	// IPConfigs are keyed by ipconfigN matching netN, for example "ipconfig0": "ip=10.0.0.5/24,gw=10.0.0.1" or "ip=dhcp"
+	IPConfigs IndexedOptions `url:"ipconfigs,omitempty"`
+}
+
+// Validate checks the configuration for mistakes Proxmox would reject or that can't be what was meant, like disks
+// with unknown keys or a boot order referencing missing devices. The returned error wraps ErrInvalidConfig.
+func (c *QemuConfig) Validate() error {
+	if c == nil {
+		return fmt.Errorf("%w: no config", ErrInvalidConfig)
+	}
+
+	var problems []string
+	if c.VMID < 100 || c.VMID > 999999999 {
+		problems = append(problems, fmt.Sprintf("vmid %d must be between 100 and 999999999", c.VMID))
+	}
+	if c.Name != "" && !guestName.MatchString(c.Name) {
+		problems = append(problems, fmt.Sprintf("name %q must be a valid DNS name", c.Name))
+	}
+	if c.BIOS != "" && c.BIOS != "seabios" && c.BIOS != "ovmf" {
+		problems = append(problems, fmt.Sprintf("bios %q must be seabios or ovmf", c.BIOS))
+	}
+	if c.Cores < 0 || c.Sockets < 0 {
+		problems = append(problems, "cores and sockets must not be negative")
+	}
+	if c.Memory != 0 && c.Memory < 16 {
+		problems = append(problems, fmt.Sprintf("memory %d MiB must be at least 16 MiB", c.Memory))
+	}
+	if c.Balloon != nil && (*c.Balloon < 0 || (c.Memory != 0 && *c.Balloon > c.Memory)) {
+		problems = append(problems, fmt.Sprintf("balloon %d MiB must be between 0 and memory", *c.Balloon))
+	}
+
+	problems = append(problems, validateIndexedOptions("disk", c.Disks, "ide", "sata", "scsi", "virtio")...)
+	problems = append(problems, validateIndexedOptions("nic", c.NICs, "net")...)
+	problems = append(problems, validateIndexedOptions("ipconfig", c.IPConfigs, "ipconfig")...)
+
+	// Disks and NICs in the boot order must be configured, other devices like hostpci0 aren't checked
+	if order, ok := strings.CutPrefix(c.Boot, "order="); ok {
+		for _, device := range strings.Split(order, ";") {
+			if _, _, ok := splitIndexedKey(device); !ok {
+				continue
+			}
+			if _, isDisk := c.Disks[device]; !isDisk {
+				if _, isNIC := c.NICs[device]; !isNIC {
+					problems = append(problems, fmt.Sprintf("boot device %s is not configured", device))
+				}
+			}
+		}
+	}
+
+	// UEFI variables are only stored with OVMF, and cloud-init settings are only passed to the VM by a cloud-init drive
+	if c.EFIDisk != "" && c.BIOS != "ovmf" {
+		problems = append(problems, "efidisk0 requires bios ovmf")
+	}
+	if c.usesCloudInit() && !c.hasCloudInitDrive() {
+		problems = append(problems, "cloud-init options require a cloud-init drive in disks, like \"ide2\": \"local-lvm:cloudinit\"")
+	}
+
+	// IP configurations apply to the NIC with the same number
+	keys := make([]string, 0, len(c.IPConfigs))
+	for key := range c.IPConfigs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		prefix, n, ok := splitIndexedKey(key)
+		if !ok || prefix != "ipconfig" {
+			continue
+		}
+		if _, ok := c.NICs["net"+strconv.Itoa(n)]; !ok {
+			problems = append(problems, fmt.Sprintf("%s requires a matching net%d", key, n))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// usesCloudInit reports whether any cloud-init option is set
+func (c *QemuConfig) usesCloudInit() bool {
+	return c.CIType != "" || c.CIUser != "" || c.CIPassword != "" || c.CIUpgrade != nil || c.SSHKeys != "" ||
+		c.Nameserver != "" || c.SearchDomain != "" || len(c.IPConfigs) > 0
+}
+
+// hasCloudInitDrive reports whether a disk is a cloud-init drive, either one to be created on a storage like
+// "local-lvm:cloudinit" or an existing one like "local-lvm:vm-100-cloudinit"
+func (c *QemuConfig) hasCloudInitDrive() bool {
+	for _, disk := range c.Disks {
+		d, err := ParseDiskDevice(disk)
+		if err != nil {
+			continue
+		}
+		_, name, ok := strings.Cut(d.Volume, ":")
+		if ok && (name == "cloudinit" || cloudInitVolume.MatchString(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateIndexedOptions checks that options have a number in range, one of the given prefixes and a value
+func validateIndexedOptions(kind string, options IndexedOptions, prefixes ...string) []string {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range keys {
+		prefix, _, ok := splitIndexedKey(key)
+		valid := false
+		for _, p := range prefixes {
+			valid = valid || (ok && prefix == p)
+		}
+		if !valid {
+			problems = append(problems, fmt.Sprintf("%s key %q must be one of %s followed by a number in range", kind, key, strings.Join(prefixes, ", ")))
+			continue
+		}
+		if options[key] == "" {
+			problems = append(problems, fmt.Sprintf("%s %s has no value", kind, key))
+		}
+	}
+	return problems
+}
+
+// splitIndexedKey splits a numbered config key like "scsi0" into its prefix and number,
+// reporting whether the prefix is known and the number within its range
+func splitIndexedKey(key string) (string, int, bool) {
+	i := strings.IndexAny(key, "0123456789")
+	if i <= 0 {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(key[i:])
+	if err != nil || n < 0 || strconv.Itoa(n) != key[i:] {
+		return "", 0, false
+	}
+	highest, ok := qemuIndexedKeys[key[:i]]
+	if !ok || n > highest {
+		return "", 0, false
+	}
+	return key[:i], n, true
+}
+
+// encodeSSHKeys encodes SSH keys for the sshkeys parameter, which Proxmox expects to be URL encoded
+// in addition to the encoding of the request body
+func encodeSSHKeys(keys string) string {
+	return strings.ReplaceAll(url.QueryEscape(keys), "+", "%20")
+}
+
+// CreateQemu makes a POST request to the /nodes/{node}/qemu endpoint
+// The config is validated before it's sent, returning an error wrapping ErrInvalidConfig if it's invalid.
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu
+func (s *NodeService) CreateQemu(nodeName string, config *QemuConfig) (*TaskResponse, *http.Response, error) {
+	return s.CreateQemuWithContext(context.Background(), nodeName, config)
+}
+
+// CreateQemuWithContext is like CreateQemu but uses the given context for the request
+func (s *NodeService) CreateQemuWithContext(ctx context.Context, nodeName string, config *QemuConfig) (*TaskResponse, *http.Response, error) {
+	if err := config.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	values, err := query.Values(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if config.SSHKeys != "" {
+		values.Set("sshkeys", encodeSSHKeys(config.SSHKeys))
+	}
+
+	u := fmt.Sprintf("nodes/%s/qemu", nodeName)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.CreateQemu"), http.MethodPost, u, values)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := new(TaskResponse)
+	resp, err := s.client.Do(req, d)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return d, resp, nil
+}
+
+// GetQemuConfigOptions contains the optional parameters for the /nodes/{node}/qemu/{vmid}/config endpoint
+type GetQemuConfigOptions struct {
+	Current  bool   `url:"current,omitempty,int"` // Get the current values instead of the values with pending changes applied
+	Snapshot string `url:"snapshot,omitempty"`    // Get the config of a snapshot instead
+}
+
+// GetQemuConfigResponse contains the response for the /nodes/{node}/qemu/{vmid}/config endpoint
+type GetQemuConfigResponse struct {
+	Data QemuConfigValues `json:"data"`
+}
+
+// QemuConfigValues contains the options of a VM configuration keyed by name, like "cores", "scsi0" or "net0".
+// Numbers are converted to strings, so every value has the format UpdateQemuConfig accepts.
+type QemuConfigValues map[string]string
+
+// UnmarshalJSON implements the json.Unmarshaler interface for QemuConfigValues
+func (v *QemuConfigValues) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	values := make(QemuConfigValues, len(raw))
+	for key, value := range raw {
+		str, err := configValueString(value)
+		if err != nil {
+			return fmt.Errorf("config option %s: %w", key, err)
+		}
+		values[key] = str
+	}
+	*v = values
+	return nil
+}
+
+// Digest returns the digest of the configuration, which can be sent back in UpdateQemuConfigOptions
+// to make the update fail if the configuration was changed in the meantime
+func (v QemuConfigValues) Digest() string {
+	return v["digest"]
+}
+
+// configValueString converts a config value Proxmox returned as a JSON string or number to a string
+func configValueString(data json.RawMessage) (string, error) {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		return str, nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return "", fmt.Errorf("expected a string or number, got %s", data)
+	}
+	return n.String(), nil
+}
+
+// GetQemuConfig makes a GET request to the /nodes/{node}/qemu/{vmid}/config endpoint
+// Pending changes are applied to the returned values unless opt.Current is set.
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu/{vmid}/config
+func (s *NodeService) GetQemuConfig(nodeName string, vmID int, opt *GetQemuConfigOptions) (*GetQemuConfigResponse, *http.Response, error) {
+	return s.GetQemuConfigWithContext(context.Background(), nodeName, vmID, opt)
+}
+
+// GetQemuConfigWithContext is like GetQemuConfig but uses the given context for the request
+func (s *NodeService) GetQemuConfigWithContext(ctx context.Context, nodeName string, vmID int, opt *GetQemuConfigOptions) (*GetQemuConfigResponse, *http.Response, error) {
+	u := fmt.Sprintf("nodes/%s/qemu/%d/config", nodeName, vmID)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.GetQemuConfig"), http.MethodGet, u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := new(GetQemuConfigResponse)
+	resp, err := s.client.Do(req, d)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return d, resp, nil
+}
+
+// UpdateQemuConfigOptions contains the parameters for the /nodes/{node}/qemu/{vmid}/config endpoint
+type UpdateQemuConfigOptions struct {
+	// Values are the options to set keyed by name, for example "memory": "8192" or "net1": "virtio,bridge=vmbr1".
+	// The parameters of the other fields, like "digest" or "delete", can't be set here.
+	Values IndexedOptions `url:"values,omitempty"`
+
+	Delete   []string `url:"delete,omitempty,comma"` // Options to remove
+	Revert   []string `url:"revert,omitempty,comma"` // Options to discard the pending changes of
+	Digest   string   `url:"digest,omitempty"`       // Fail with a conflict if the config's digest changed, see QemuConfigValues.Digest
+	Force    bool     `url:"force,omitempty,int"`    // Destroy removed disks instead of keeping them as unusedN
+	SkipLock bool     `url:"skiplock,omitempty,int"` // Ignore locks, only root is allowed to use this option
+}
+
+// updateQemuConfigParams are the parameters of UpdateQemuConfigOptions fields, by the name of the field
+var updateQemuConfigParams = map[string]string{
+	"delete":   "Delete",
+	"revert":   "Revert",
+	"digest":   "Digest",
+	"force":    "Force",
+	"skiplock": "SkipLock",
+}
+
+// validate checks that Values doesn't contain the parameters of the other fields, which would silently replace them
+func (o *UpdateQemuConfigOptions) validate() error {
+	if o == nil {
+		return nil
+	}
+
+	var problems []string
+	for key := range o.Values {
+		if field, ok := updateQemuConfigParams[key]; ok {
+			problems = append(problems, fmt.Sprintf("%s can't be set in values, use the %s field", key, field))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// UpdateQemuConfig makes a PUT request to the /nodes/{node}/qemu/{vmid}/config endpoint
+// Changes that can't be applied to a running VM, or that need a new disk to be allocated, become pending
+// and are applied on the next start or reboot. Use UpdateQemuConfigAsync for changes that take long.
+// If opt.Digest is set and the config was changed in the meantime the returned error satisfies IsConfigConflict.
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu/{vmid}/config
+func (s *NodeService) UpdateQemuConfig(nodeName string, vmID int, opt *UpdateQemuConfigOptions) (*http.Response, error) {
+	return s.UpdateQemuConfigWithContext(context.Background(), nodeName, vmID, opt)
+}
+
+// UpdateQemuConfigWithContext is like UpdateQemuConfig but uses the given context for the request
+func (s *NodeService) UpdateQemuConfigWithContext(ctx context.Context, nodeName string, vmID int, opt *UpdateQemuConfigOptions) (*http.Response, error) {
+	return s.updateQemuConfig(withOperation(ctx, "NodeService.UpdateQemuConfig"), nodeName, vmID, opt)
+}
+
+// UpdateQemuConfigAsync makes a POST request to the /nodes/{node}/qemu/{vmid}/config endpoint
+// It's like UpdateQemuConfig, but the changes are made by a task instead of during the request.
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu/{vmid}/config
+func (s *NodeService) UpdateQemuConfigAsync(nodeName string, vmID int, opt *UpdateQemuConfigOptions) (*TaskResponse, *http.Response, error) {
+	return s.UpdateQemuConfigAsyncWithContext(context.Background(), nodeName, vmID, opt)
+}
+
+// UpdateQemuConfigAsyncWithContext is like UpdateQemuConfigAsync but uses the given context for the request
+func (s *NodeService) UpdateQemuConfigAsyncWithContext(ctx context.Context, nodeName string, vmID int, opt *UpdateQemuConfigOptions) (*TaskResponse, *http.Response, error) {
+	if err := opt.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	u := fmt.Sprintf("nodes/%s/qemu/%d/config", nodeName, vmID)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.UpdateQemuConfigAsync"), http.MethodPost, u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := new(TaskResponse)
+	resp, err := s.client.Do(req, d)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return d, resp, nil
+}
+
+// RevertQemuPending makes a PUT request to the /nodes/{node}/qemu/{vmid}/config endpoint
+// It discards the pending changes of the given options, keeping their current values.
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu/{vmid}/config
+func (s *NodeService) RevertQemuPending(nodeName string, vmID int, keys ...string) (*http.Response, error) {
+	return s.RevertQemuPendingWithContext(context.Background(), nodeName, vmID, keys...)
+}
+
+// RevertQemuPendingWithContext is like RevertQemuPending but uses the given context for the request
+func (s *NodeService) RevertQemuPendingWithContext(ctx context.Context, nodeName string, vmID int, keys ...string) (*http.Response, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no pending options to revert")
+	}
+	return s.updateQemuConfig(withOperation(ctx, "NodeService.RevertQemuPending"), nodeName, vmID, &UpdateQemuConfigOptions{Revert: keys})
+}
+
+// updateQemuConfig makes a PUT request to the /nodes/{node}/qemu/{vmid}/config endpoint
+func (s *NodeService) updateQemuConfig(ctx context.Context, nodeName string, vmID int, opt *UpdateQemuConfigOptions) (*http.Response, error) {
+	if err := opt.validate(); err != nil {
+		return nil, err
+	}
+
+	u := fmt.Sprintf("nodes/%s/qemu/%d/config", nodeName, vmID)
+	req, err := s.client.NewRequestWithContext(ctx, http.MethodPut, u, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.client.Do(req, nil)
+}
+
+// GetQemuPendingResponse contains the response for the /nodes/{node}/qemu/{vmid}/pending endpoint
+type GetQemuPendingResponse struct {
+	Data []GetQemuPendingData `json:"data"`
+}
+
+// GetQemuPendingData contains an option of a VM configuration and its pending change from a GetQemuPending response.
+// Values are converted to strings like those of QemuConfigValues.
+type GetQemuPendingData struct {
+	Key     string  `json:"key"`
+	Value   *string `json:"value,omitempty"`   // Current value, nil if the option is only pending
+	Pending *string `json:"pending,omitempty"` // Value set on the next start or reboot, nil if there's no pending change
+	Delete  *int    `json:"delete,omitempty"`  // 1 if the option is removed on the next start or reboot, 2 if it's forced
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for GetQemuPendingData
+func (d *GetQemuPendingData) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Key     string          `json:"key"`
+		Value   json.RawMessage `json:"value"`
+		Pending json.RawMessage `json:"pending"`
+		Delete  *int            `json:"delete"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	value, err := optionalConfigValue(raw.Value)
+	if err != nil {
+		return fmt.Errorf("pending option %s: %w", raw.Key, err)
+	}
+	pending, err := optionalConfigValue(raw.Pending)
+	if err != nil {
+		return fmt.Errorf("pending option %s: %w", raw.Key, err)
+	}
+
+	*d = GetQemuPendingData{Key: raw.Key, Value: value, Pending: pending, Delete: raw.Delete}
+	return nil
+}
+
+// optionalConfigValue is like configValueString but returns nil for a missing or null value
+func optionalConfigValue(data json.RawMessage) (*string, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	str, err := configValueString(data)
+	if err != nil {
+		return nil, err
+	}
+	return &str, nil
+}
+
+// GetQemuPending makes a GET request to the /nodes/{node}/qemu/{vmid}/pending endpoint
+// It returns every option of the VM configuration along with its pending change, if any.
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/qemu/{vmid}/pending
+func (s *NodeService) GetQemuPending(nodeName string, vmID int) (*GetQemuPendingResponse, *http.Response, error) {
+	return s.GetQemuPendingWithContext(context.Background(), nodeName, vmID)
+}
+
+// GetQemuPendingWithContext is like GetQemuPending but uses the given context for the request
+func (s *NodeService) GetQemuPendingWithContext(ctx context.Context, nodeName string, vmID int) (*GetQemuPendingResponse, *http.Response, error) {
+	u := fmt.Sprintf("nodes/%s/qemu/%d/pending", nodeName, vmID)
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "NodeService.GetQemuPending"), http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := new(GetQemuPendingResponse)
+	resp, err := s.client.Do(req, d)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return d, resp, nil
+}
@@ -0,0 +1,387 @@
+package proxmox
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetQemuStatusCurrent(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/100/status/current", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		w.Header().Set("Content-Type", "application/json")
+		_, err := fmt.Fprint(w, fixture("nodes/get_qemu_status_current.json"))
+		if err != nil {
+			return
+		}
+	})
+
+	r, resp, err := client.Nodes.GetQemuStatusCurrent("srv1", 100)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+
+	d := r.Data
+	require.Equal(t, "running", d.Status)
+	require.Equal(t, IntOrString("100"), d.VMID)
+	require.Equal(t, "running", *d.QMPStatus)
+	require.Equal(t, "pc-i440fx-8.1+pve0", *d.RunningMachine)
+	require.Equal(t, 1, *d.Agent)
+	require.Equal(t, QemuHAStatus{Managed: 1, Group: testStr("prod"), State: testStr("started")}, d.HA)
+	require.Equal(t, int64(4294967296), d.BalloonInfo.Actual)
+	require.Equal(t, int64(2723708928), *d.BalloonInfo.FreeMem)
+	require.Equal(t, QemuNICStat{NetIn: 123456, NetOut: 654321}, d.NICs["tap100i0"])
+	require.Nil(t, d.Clipboard)
+	require.Nil(t, d.Lock)
+	require.Equal(t, "1.4.1 (UNKNOWN)", d.ProxmoxSupport["pbs-library-version"])
+}
+
+func TestQemuPower(t *testing.T) {
+	tests := []struct {
+		action string
+		call   func(c *Client) (*TaskResponse, *http.Response, error)
+		form   url.Values
+	}{
+		{
+			action: "start",
+			call: func(c *Client) (*TaskResponse, *http.Response, error) {
+				return c.Nodes.StartQemu("srv1", 100, &StartQemuOptions{MigratedFrom: "srv2", SkipLock: true, Timeout: 30})
+			},
+			form: url.Values{"migratedfrom": {"srv2"}, "skiplock": {"1"}, "timeout": {"30"}},
+		},
+		{
+			action: "stop",
+			call: func(c *Client) (*TaskResponse, *http.Response, error) {
+				return c.Nodes.StopQemu("srv1", 100, &StopQemuOptions{KeepActive: true})
+			},
+			form: url.Values{"keepActive": {"1"}},
+		},
+		{
+			action: "shutdown",
+			call: func(c *Client) (*TaskResponse, *http.Response, error) {
+				return c.Nodes.ShutdownQemu("srv1", 100, &ShutdownQemuOptions{ForceStop: true, Timeout: 60})
+			},
+			form: url.Values{"forceStop": {"1"}, "timeout": {"60"}},
+		},
+		{
+			action: "reboot",
+			call: func(c *Client) (*TaskResponse, *http.Response, error) {
+				return c.Nodes.RebootQemu("srv1", 100, nil)
+			},
+			form: url.Values{},
+		},
+		{
+			action: "reset",
+			call: func(c *Client) (*TaskResponse, *http.Response, error) {
+				return c.Nodes.ResetQemu("srv1", 100, &ResetQemuOptions{SkipLock: true})
+			},
+			form: url.Values{"skiplock": {"1"}},
+		},
+		{
+			action: "suspend",
+			call: func(c *Client) (*TaskResponse, *http.Response, error) {
+				return c.Nodes.SuspendQemu("srv1", 100, &SuspendQemuOptions{ToDisk: true, StateStorage: "local-lvm"})
+			},
+			form: url.Values{"todisk": {"1"}, "statestorage": {"local-lvm"}},
+		},
+		{
+			action: "resume",
+			call: func(c *Client) (*TaskResponse, *http.Response, error) {
+				return c.Nodes.ResumeQemu("srv1", 100, nil)
+			},
+			form: url.Values{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			mux, server, client := setup(t)
+			defer teardown(server)
+
+			upid := fmt.Sprintf("UPID:srv1:00001234:00005678:65A1B2C3:qm%s:100:root@pam:", tt.action)
+			var form url.Values
+			mux.HandleFunc("/api2/json/nodes/srv1/qemu/100/status/"+tt.action, func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+				require.NoError(t, r.ParseForm())
+				form = r.PostForm
+				_, _ = fmt.Fprintf(w, `{"data":%q}`, upid)
+			})
+
+			r, _, err := tt.call(client)
+			require.NoError(t, err)
+			require.Equal(t, upid, r.Data)
+			require.Equal(t, tt.form, form)
+		})
+	}
+}
+
+func TestStopQemuOverruleShutdown(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithServerVersion("8.0.4")(client))
+
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/100/status/stop", func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request sent to a server without overrule-shutdown support")
+	})
+
+	_, _, err := client.Nodes.StopQemu("srv1", 100, &StopQemuOptions{OverruleShutdown: true})
+	require.ErrorIs(t, err, ErrUnsupportedVersion)
+}
+
+func TestCreateQemu(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	var form url.Values
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+		_, _ = fmt.Fprint(w, `{"data":"UPID:srv1:00001234:00005678:65A1B2C3:qmcreate:110:root@pam:"}`)
+	})
+
+	balloon, upgrade := 0, false
+	r, _, err := client.Nodes.CreateQemu("srv1", &QemuConfig{
+		VMID:    110,
+		Name:    "web-01",
+		OSType:  "l26",
+		Machine: "q35",
+		BIOS:    "ovmf",
+		CPU:     "host",
+		Cores:   4,
+		Sockets: 1,
+		Memory:  4096,
+		Balloon: &balloon,
+		SCSIHW:  "virtio-scsi-single",
+		Disks: IndexedOptions{
+			"scsi0": "local-lvm:32,discard=on,iothread=1",
+			"ide2":  "local-lvm:cloudinit",
+		},
+		NICs:       IndexedOptions{"net0": "virtio,bridge=vmbr0,firewall=1,tag=20"},
+		EFIDisk:    "local-lvm:1,efitype=4m,pre-enrolled-keys=1",
+		TPMState:   "local-lvm:1,version=v2.0",
+		Boot:       "order=scsi0;net0",
+		OnBoot:     true,
+		CIUser:     "ops",
+		CIUpgrade:  &upgrade,
+		SSHKeys:    "ssh-ed25519 AAAAC3Nza ops@example.com",
+		IPConfigs:  IndexedOptions{"ipconfig0": "ip=10.0.0.5/24,gw=10.0.0.1"},
+		Nameserver: "10.0.0.53",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "UPID:srv1:00001234:00005678:65A1B2C3:qmcreate:110:root@pam:", r.Data)
+
+	require.Equal(t, url.Values{
+		"vmid":       {"110"},
+		"name":       {"web-01"},
+		"ostype":     {"l26"},
+		"machine":    {"q35"},
+		"bios":       {"ovmf"},
+		"cpu":        {"host"},
+		"cores":      {"4"},
+		"sockets":    {"1"},
+		"memory":     {"4096"},
+		"balloon":    {"0"},
+		"scsihw":     {"virtio-scsi-single"},
+		"scsi0":      {"local-lvm:32,discard=on,iothread=1"},
+		"ide2":       {"local-lvm:cloudinit"},
+		"net0":       {"virtio,bridge=vmbr0,firewall=1,tag=20"},
+		"efidisk0":   {"local-lvm:1,efitype=4m,pre-enrolled-keys=1"},
+		"tpmstate0":  {"local-lvm:1,version=v2.0"},
+		"boot":       {"order=scsi0;net0"},
+		"onboot":     {"1"},
+		"ciuser":     {"ops"},
+		"ciupgrade":  {"0"},
+		"sshkeys":    {"ssh-ed25519%20AAAAC3Nza%20ops%40example.com"},
+		"ipconfig0":  {"ip=10.0.0.5/24,gw=10.0.0.1"},
+		"nameserver": {"10.0.0.53"},
+	}, form)
+}
+
+func TestCreateQemuInvalid(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu", func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("invalid config sent")
+	})
+
+	_, _, err := client.Nodes.CreateQemu("srv1", &QemuConfig{VMID: 42})
+	require.ErrorIs(t, err, ErrInvalidConfig)
+
+	_, _, err = client.Nodes.CreateQemu("srv1", nil)
+	require.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+func TestQemuConfigValidate(t *testing.T) {
+	balloon := 8192
+	tests := map[string]struct {
+		config  QemuConfig
+		problem string
+	}{
+		"minimal":          {config: QemuConfig{VMID: 100}},
+		"vmid":             {config: QemuConfig{}, problem: "vmid 0 must be between 100 and 999999999"},
+		"name":             {config: QemuConfig{VMID: 100, Name: "web_01"}, problem: `name "web_01" must be a valid DNS name`},
+		"bios":             {config: QemuConfig{VMID: 100, BIOS: "uefi"}, problem: `bios "uefi" must be seabios or ovmf`},
+		"memory":           {config: QemuConfig{VMID: 100, Memory: 8}, problem: "memory 8 MiB must be at least 16 MiB"},
+		"balloon":          {config: QemuConfig{VMID: 100, Memory: 4096, Balloon: &balloon}, problem: "balloon 8192 MiB must be between 0 and memory"},
+		"disk key":         {config: QemuConfig{VMID: 100, Disks: IndexedOptions{"scsi31": "local-lvm:32"}}, problem: `disk key "scsi31" must be one of ide, sata, scsi, virtio followed by a number in range`},
+		"disk as nic":      {config: QemuConfig{VMID: 100, NICs: IndexedOptions{"scsi0": "local-lvm:32"}}, problem: `nic key "scsi0" must be one of net followed by a number in range`},
+		"disk value":       {config: QemuConfig{VMID: 100, Disks: IndexedOptions{"virtio0": ""}}, problem: "disk virtio0 has no value"},
+		"ipconfig":         {config: QemuConfig{VMID: 100, IPConfigs: IndexedOptions{"ipconfig01": "ip=dhcp"}}, problem: `ipconfig key "ipconfig01" must be one of ipconfig followed by a number in range`},
+		"boot order":       {config: QemuConfig{VMID: 100, Disks: IndexedOptions{"scsi0": "local-lvm:32"}, Boot: "order=scsi0;net0"}, problem: "boot device net0 is not configured"},
+		"boot passthrough": {config: QemuConfig{VMID: 100, Boot: "order=hostpci0"}},
+		"efidisk":          {config: QemuConfig{VMID: 100, EFIDisk: "local-lvm:1,efitype=4m"}, problem: "efidisk0 requires bios ovmf"},
+		"efidisk seabios":  {config: QemuConfig{VMID: 100, BIOS: "seabios", EFIDisk: "local-lvm:1"}, problem: "efidisk0 requires bios ovmf"},
+		"efidisk ovmf":     {config: QemuConfig{VMID: 100, BIOS: "ovmf", EFIDisk: "local-lvm:1,efitype=4m"}},
+		"cloud-init":       {config: QemuConfig{VMID: 100, CIUser: "ops"}, problem: "cloud-init options require a cloud-init drive"},
+		"cloud-init ip":    {config: QemuConfig{VMID: 100, Disks: IndexedOptions{"scsi0": "local-lvm:32"}, IPConfigs: IndexedOptions{"ipconfig0": "ip=dhcp"}}, problem: "cloud-init options require a cloud-init drive"},
+		"cloud-init keys":  {config: QemuConfig{VMID: 100, SSHKeys: "ssh-ed25519 AAAAC3Nza ops@example.com"}, problem: "cloud-init options require a cloud-init drive"},
+		"cloud-init drive": {config: QemuConfig{VMID: 100, Disks: IndexedOptions{"ide2": "local-lvm:cloudinit,media=cdrom"}, NICs: IndexedOptions{"net0": "virtio,bridge=vmbr0"}, CIUser: "ops", IPConfigs: IndexedOptions{"ipconfig0": "ip=dhcp"}}},
+		"cloud-init lvm":   {config: QemuConfig{VMID: 100, Disks: IndexedOptions{"ide2": "local-lvm:vm-100-cloudinit,media=cdrom"}, CIUser: "ops"}},
+		"cloud-init dir":   {config: QemuConfig{VMID: 100, Disks: IndexedOptions{"ide2": "local:100/vm-100-cloudinit.qcow2,media=cdrom"}, CIUser: "ops"}},
+		"cloud-init store": {config: QemuConfig{VMID: 100, Disks: IndexedOptions{"scsi0": "cloudinit-store:vm-100-disk-0"}, CIUser: "ops"}, problem: "cloud-init options require a cloud-init drive"},
+		"ipconfig nic":     {config: QemuConfig{VMID: 100, Disks: IndexedOptions{"ide2": "local-lvm:cloudinit"}, NICs: IndexedOptions{"net0": "virtio,bridge=vmbr0"}, IPConfigs: IndexedOptions{"ipconfig0": "ip=dhcp", "ipconfig1": "ip=10.0.0.5/24"}}, problem: "ipconfig1 requires a matching net1"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.problem == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrInvalidConfig)
+			require.Contains(t, err.Error(), tt.problem)
+		})
+	}
+}
+
+func TestGetQemuConfig(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/100/config", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		require.Equal(t, "1", r.URL.Query().Get("current"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, fixture("nodes/get_qemu_config.json"))
+	})
+
+	r, _, err := client.Nodes.GetQemuConfig("srv1", 100, &GetQemuConfigOptions{Current: true})
+	require.NoError(t, err)
+
+	d := r.Data
+	require.Equal(t, "4f2d8c7e21a0b6e7f5e1b0e3c1a9d8f7e6c5b4a3", d.Digest())
+	require.Equal(t, "2", d["cores"])
+	require.Equal(t, "4096", d["memory"])
+	require.Equal(t, "2048", d["balloon"])
+	require.Equal(t, "0", d["numa"])
+	require.Equal(t, "local-lvm:vm-100-disk-0,discard=on,iothread=1,size=32G", d["scsi0"])
+	require.Equal(t, "virtio=BC:24:11:2E:7A:10,bridge=vmbr0,firewall=1,tag=20", d["net0"])
+}
+
+func TestUpdateQemuConfig(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	var form url.Values
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/100/config", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+		switch r.Method {
+		case http.MethodPut:
+			_, _ = fmt.Fprint(w, `{"data":null}`)
+		case http.MethodPost:
+			_, _ = fmt.Fprint(w, `{"data":"UPID:srv1:00001234:00005678:65A1B2C3:qmconfig:100:root@pam:"}`)
+		default:
+			t.Fatalf("unexpected method %s", r.Method)
+		}
+	})
+
+	opt := &UpdateQemuConfigOptions{
+		Values: IndexedOptions{"memory": "8192", "net1": "virtio,bridge=vmbr1"},
+		Delete: []string{"tablet", "unused0"},
+		Digest: "4f2d8c7e21a0b6e7f5e1b0e3c1a9d8f7e6c5b4a3",
+	}
+	want := url.Values{
+		"memory": {"8192"},
+		"net1":   {"virtio,bridge=vmbr1"},
+		"delete": {"tablet,unused0"},
+		"digest": {"4f2d8c7e21a0b6e7f5e1b0e3c1a9d8f7e6c5b4a3"},
+	}
+
+	_, err := client.Nodes.UpdateQemuConfig("srv1", 100, opt)
+	require.NoError(t, err)
+	require.Equal(t, want, form)
+
+	task, _, err := client.Nodes.UpdateQemuConfigAsync("srv1", 100, opt)
+	require.NoError(t, err)
+	require.Equal(t, "UPID:srv1:00001234:00005678:65A1B2C3:qmconfig:100:root@pam:", task.Data)
+	require.Equal(t, want, form)
+
+	_, err = client.Nodes.RevertQemuPending("srv1", 100, "cores", "net1")
+	require.NoError(t, err)
+	require.Equal(t, url.Values{"revert": {"cores,net1"}}, form)
+
+	_, err = client.Nodes.RevertQemuPending("srv1", 100)
+	require.Error(t, err)
+}
+
+func TestUpdateQemuConfigConflict(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/100/config", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprint(w, `{"data":null,"message":"detected modified configuration - file changed by other user? Try again.\n"}`)
+	})
+
+	_, err := client.Nodes.UpdateQemuConfig("srv1", 100, &UpdateQemuConfigOptions{Values: IndexedOptions{"cores": "4"}, Digest: "stale"})
+	require.True(t, IsConfigConflict(err))
+	require.False(t, IsConfigLocked(err))
+}
+
+func TestUpdateQemuConfigReservedValues(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/100/config", func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request sent with reserved values")
+	})
+
+	opt := &UpdateQemuConfigOptions{Values: IndexedOptions{"cores": "4", "digest": "stale", "skiplock": "1"}, Digest: "4f2d8c7e"}
+	_, err := client.Nodes.UpdateQemuConfig("srv1", 100, opt)
+	require.ErrorIs(t, err, ErrInvalidConfig)
+	require.ErrorContains(t, err, "digest can't be set in values, use the Digest field; skiplock can't be set in values, use the SkipLock field")
+
+	_, _, err = client.Nodes.UpdateQemuConfigAsync("srv1", 100, &UpdateQemuConfigOptions{Values: IndexedOptions{"delete": "tablet"}})
+	require.ErrorIs(t, err, ErrInvalidConfig)
+}
+
+func TestGetQemuPending(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/100/pending", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprint(w, fixture("nodes/get_qemu_pending.json"))
+	})
+
+	r, _, err := client.Nodes.GetQemuPending("srv1", 100)
+	require.NoError(t, err)
+	require.Equal(t, []GetQemuPendingData{
+		{Key: "cores", Value: testStr("2"), Pending: testStr("4")},
+		{Key: "memory", Value: testStr("4096")},
+		{Key: "name", Value: testStr("web")},
+		{Key: "net1", Pending: testStr("virtio=BC:24:11:5A:0C:31,bridge=vmbr1")},
+		{Key: "tablet", Value: testStr("1"), Delete: testInt(1)},
+	}, r.Data)
+}
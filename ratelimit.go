@@ -0,0 +1,253 @@
+package proxmox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimit configures how fast and how many requests the client sends at the same time, to avoid saturating
+// the pveproxy worker pool when fanning out requests, e.g. across every node of a cluster.
+// Zero values mean no limit.
+type RateLimit struct {
+	// RequestsPerSecond is the sustained rate requests are sent at.
+	// Default: no limit
+	RequestsPerSecond float64
+
+	// Burst is the number of requests that can be sent at once before RequestsPerSecond applies.
+	// Default: 1
+	Burst int
+
+	// MaxInFlight is the number of requests that can be in flight at the same time. A request is in flight until its
+	// response body was read, including its retries.
+	// Default: no limit
+	MaxInFlight int
+}
+
+// WithRateLimit limits the requests of the client, counting every request regardless of its path.
+// Requests wait for their turn until their context is done.
+// Default: requests are not limited
+func WithRateLimit(limit RateLimit) ClientOptionFunc {
+	return func(c *Client) error {
+		l, err := newLimiter(limit)
+		if err != nil {
+			return err
+		}
+		c.limits().global = l
+		return nil
+	}
+}
+
+// WithNodeRateLimit limits the requests of the client to each node separately, counting the requests to paths under
+// nodes/{node}. It can be combined with WithRateLimit, in which case a request has to satisfy both limits.
+// Default: requests are not limited
+func WithNodeRateLimit(limit RateLimit) ClientOptionFunc {
+	return func(c *Client) error {
+		if _, err := newLimiter(limit); err != nil {
+			return err
+		}
+		l := c.limits()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.node = &limit
+		l.nodes = map[string]*limiter{}
+		return nil
+	}
+}
+
+// limits returns the rate limits of the client, creating them on first use
+func (c *Client) limits() *rateLimits {
+	if c.rateLimits == nil {
+		c.rateLimits = &rateLimits{}
+	}
+	return c.rateLimits
+}
+
+// doLimited waits until the rate limits allow sending an API request, then authenticates and sends it
+func (c *Client) doLimited(req *http.Request, v interface{}) (*http.Response, error) {
+	if c.rateLimits == nil {
+		return c.doAuthenticated(req, v)
+	}
+
+	release, err := c.rateLimits.acquire(req.Context(), c.operation(req).Node)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return c.doAuthenticated(req, v)
+}
+
+// rateLimits holds the global limiter and the limiters of each node
+type rateLimits struct {
+	// global limits all requests, nil if not limited
+	global *limiter
+
+	mu sync.Mutex
+
+	// node is the limit of each node, nil if not limited
+	node *RateLimit
+
+	// nodes are the limiters created for each node so far
+	nodes map[string]*limiter
+}
+
+// acquire waits until the global and node limits allow sending a request.
+// The returned function must be called once the request is done.
+func (r *rateLimits) acquire(ctx context.Context, node string) (func(), error) {
+	releaseGlobal := func() {}
+	if r.global != nil {
+		var err error
+		if releaseGlobal, err = r.global.acquire(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	l := r.nodeLimiter(node)
+	if l == nil {
+		return releaseGlobal, nil
+	}
+
+	releaseNode, err := l.acquire(ctx)
+	if err != nil {
+		releaseGlobal()
+		return nil, err
+	}
+
+	return func() {
+		releaseNode()
+		releaseGlobal()
+	}, nil
+}
+
+// nodeLimiter returns the limiter of a node, nil if requests to the node aren't limited
+func (r *rateLimits) nodeLimiter(node string) *limiter {
+	if node == "" {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.node == nil {
+		return nil
+	}
+	l, ok := r.nodes[node]
+	if !ok {
+		// The limit was validated by WithNodeRateLimit
+		l, _ = newLimiter(*r.node)
+		r.nodes[node] = l
+	}
+	return l
+}
+
+// limiter enforces a RateLimit with a token bucket and a semaphore
+type limiter struct {
+	// bucket limits the request rate, nil if not limited
+	bucket *tokenBucket
+
+	// inFlight holds a value for every request in flight, nil if not limited
+	inFlight chan struct{}
+}
+
+// newLimiter returns a limiter for a RateLimit
+func newLimiter(limit RateLimit) (*limiter, error) {
+	if limit.RequestsPerSecond < 0 || limit.Burst < 0 || limit.MaxInFlight < 0 {
+		return nil, errors.New("rate limit values must not be negative")
+	}
+
+	l := &limiter{}
+	if limit.RequestsPerSecond > 0 {
+		burst := limit.Burst
+		if burst == 0 {
+			burst = 1
+		}
+		l.bucket = newTokenBucket(limit.RequestsPerSecond, burst)
+	}
+	if limit.MaxInFlight > 0 {
+		l.inFlight = make(chan struct{}, limit.MaxInFlight)
+	}
+
+	return l, nil
+}
+
+// acquire waits for a free in flight slot and then for a token.
+// The returned function must be called once the request is done.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	release := func() {}
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+			release = func() { <-l.inFlight }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if l.bucket != nil {
+		if err := l.bucket.wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
+
+// tokenBucket is a token bucket refilled at a constant rate
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a full token bucket
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait takes a token from the bucket, waiting until one is available or the context is done
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	// Reserve a token, going into debt if none is available, so waiting requests are served in order
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Return the reserved token
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
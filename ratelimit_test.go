@@ -0,0 +1,161 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// inFlightHandler blocks every request until release is closed and records the most requests in flight at once
+func inFlightHandler(release <-chan struct{}, arrived chan<- string, peak *int32) http.HandlerFunc {
+	var current int32
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			m := atomic.LoadInt32(peak)
+			if n <= m || atomic.CompareAndSwapInt32(peak, m, n) {
+				break
+			}
+		}
+		arrived <- r.URL.Path
+		<-release
+		_, _ = fmt.Fprint(w, `{"data":[]}`)
+	}
+}
+
+func TestWithRateLimitMaxInFlight(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithRateLimit(RateLimit{MaxInFlight: 2})(client))
+
+	release := make(chan struct{})
+	arrived := make(chan string, 10)
+	var peak int32
+	mux.HandleFunc("/api2/json/nodes", inFlightHandler(release, arrived, &peak))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 6)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, errs[i] = client.Nodes.GetNodes()
+		}(i)
+	}
+
+	// Two requests are sent right away, the others wait for a free slot
+	<-arrived
+	<-arrived
+	select {
+	case <-arrived:
+		t.Fatal("more than 2 requests in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&peak))
+}
+
+func TestWithRateLimitRequestsPerSecond(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithRateLimit(RateLimit{RequestsPerSecond: 50, Burst: 2})(client))
+
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"data":[]}`)
+	})
+
+	// The burst is sent right away, the 3 requests after it are spaced 20ms apart
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		_, _, err := client.Nodes.GetNodes()
+		require.NoError(t, err)
+	}
+	require.GreaterOrEqual(t, time.Since(start), 55*time.Millisecond)
+}
+
+func TestWithRateLimitContext(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithRateLimit(RateLimit{MaxInFlight: 1})(client))
+
+	release := make(chan struct{})
+	arrived := make(chan string, 1)
+	var peak int32
+	mux.HandleFunc("/api2/json/nodes", inFlightHandler(release, arrived, &peak))
+
+	done := make(chan error)
+	go func() {
+		_, _, err := client.Nodes.GetNodes()
+		done <- err
+	}()
+	<-arrived
+
+	// The second request gives up waiting for the slot of the first
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, resp, err := client.Nodes.GetNodesWithContext(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Nil(t, resp)
+
+	close(release)
+	require.NoError(t, <-done)
+}
+
+func TestWithNodeRateLimit(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithNodeRateLimit(RateLimit{MaxInFlight: 1})(client))
+
+	release := make(chan struct{})
+	arrived := make(chan string, 10)
+	var peak int32
+	handler := inFlightHandler(release, arrived, &peak)
+	mux.HandleFunc("/api2/json/nodes/node1/qemu", handler)
+	mux.HandleFunc("/api2/json/nodes/node2/qemu", handler)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, errs[i] = client.Nodes.GetNodeQemu(fmt.Sprintf("node%d", i%2+1))
+		}(i)
+	}
+
+	// One request per node is sent right away
+	first, second := <-arrived, <-arrived
+	require.ElementsMatch(t, []string{"/api2/json/nodes/node1/qemu", "/api2/json/nodes/node2/qemu"}, []string{first, second})
+	select {
+	case <-arrived:
+		t.Fatal("more than 1 request in flight to a node")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&peak))
+}
+
+func TestWithRateLimitInvalid(t *testing.T) {
+	_, err := NewClient("id", "token", WithRateLimit(RateLimit{RequestsPerSecond: -1}))
+	require.Error(t, err)
+
+	_, err = NewClient("id", "token", WithNodeRateLimit(RateLimit{MaxInFlight: -1}))
+	require.Error(t, err)
+}
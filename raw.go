@@ -0,0 +1,56 @@
+package proxmox
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// dataEnvelope is the {"data": ...} object the Proxmox API wraps every response in
+type dataEnvelope[T any] struct {
+	Data T `json:"data"`
+}
+
+// Get makes a GET request to any API path, like pvesh get, and decodes the data field of the response into T.
+// Use it for endpoints that don't have a dedicated method yet. The path may start with a slash, e.g. "/nodes/srv1/qemu".
+// Params are encoded into the query string, see NewRequest for the supported types. It may be nil.
+func Get[T any](ctx context.Context, c *Client, path string, params interface{}) (T, *http.Response, error) {
+	return call[T](ctx, c, http.MethodGet, path, params)
+}
+
+// Post makes a POST request to any API path, like pvesh create, and decodes the data field of the response into T.
+// Endpoints that start a task return its UPID as a string. See Get for details on the other arguments.
+func Post[T any](ctx context.Context, c *Client, path string, params interface{}) (T, *http.Response, error) {
+	return call[T](ctx, c, http.MethodPost, path, params)
+}
+
+// Put makes a PUT request to any API path, like pvesh set, and decodes the data field of the response into T.
+// Most PUT endpoints return no data, use any or json.RawMessage for T if the result is not needed.
+// See Get for details on the other arguments.
+func Put[T any](ctx context.Context, c *Client, path string, params interface{}) (T, *http.Response, error) {
+	return call[T](ctx, c, http.MethodPut, path, params)
+}
+
+// Delete makes a DELETE request to any API path, like pvesh delete, and decodes the data field of the response into T.
+// See Get for details on the other arguments.
+func Delete[T any](ctx context.Context, c *Client, path string, params interface{}) (T, *http.Response, error) {
+	return call[T](ctx, c, http.MethodDelete, path, params)
+}
+
+// call sends a request to an API path and unwraps the data envelope of its response
+func call[T any](ctx context.Context, c *Client, method, path string, params interface{}) (T, *http.Response, error) {
+	var zero T
+
+	req, err := c.NewRequestWithContext(ctx, method, strings.TrimPrefix(path, "/"), params)
+	if err != nil {
+		return zero, nil, err
+	}
+
+	d := new(dataEnvelope[T])
+	resp, err := c.Do(req, d)
+	if err != nil {
+		return zero, resp, err
+	}
+
+	return d.Data, resp, nil
+}
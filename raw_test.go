@@ -0,0 +1,93 @@
+package proxmox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	var query url.Values
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		_, err := fmt.Fprint(w, fixture("nodes/get_node_qemu.json"))
+		if err != nil {
+			return
+		}
+	})
+
+	// Typed decoding into existing response types
+	vms, resp, err := Get[[]GetNodeQemuData](context.Background(), client, "/nodes/srv1/qemu", map[string]string{"full": "1"})
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.NotEmpty(t, vms)
+	require.Equal(t, "1", query.Get("full"))
+
+	want, _, err := client.Nodes.GetNodeQemu("srv1")
+	require.NoError(t, err)
+	require.Equal(t, want.Data, vms)
+
+	// Or into generic values
+	raw, _, err := Get[[]map[string]interface{}](context.Background(), client, "nodes/srv1/qemu", url.Values{"full": {"1"}})
+	require.NoError(t, err)
+	require.Len(t, raw, len(vms))
+	require.Equal(t, "1", query.Get("full"))
+
+	// Errors return the zero value
+	vms, resp, err = Get[[]GetNodeQemuData](context.Background(), client, "nodes/srv2/qemu", nil)
+	require.True(t, IsNotFound(err))
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Nil(t, vms)
+}
+
+func TestPost(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/100/status/start", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "1", r.PostForm.Get("skiplock"))
+		_, _ = fmt.Fprintf(w, `{"data":"%s"}`, testUPID)
+	})
+
+	type startOptions struct {
+		SkipLock bool `url:"skiplock,int"`
+	}
+	upid, _, err := Post[string](context.Background(), client, "nodes/srv1/qemu/100/status/start", &startOptions{SkipLock: true})
+	require.NoError(t, err)
+	require.Equal(t, testUPID, upid)
+}
+
+func TestPutAndDelete(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/100/config", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPut, r.Method)
+		require.NoError(t, r.ParseForm())
+		require.Equal(t, "web", r.PostForm.Get("name"))
+		_, _ = fmt.Fprint(w, `{"data":null}`)
+	})
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/100", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodDelete, r.Method)
+		require.Equal(t, "1", r.URL.Query().Get("purge"))
+		_, _ = fmt.Fprintf(w, `{"data":"%s"}`, testUPID)
+	})
+
+	data, _, err := Put[json.RawMessage](context.Background(), client, "nodes/srv1/qemu/100/config", map[string]string{"name": "web"})
+	require.NoError(t, err)
+	require.Equal(t, "null", string(data))
+
+	upid, _, err := Delete[string](context.Background(), client, "nodes/srv1/qemu/100", map[string]string{"purge": "1"})
+	require.NoError(t, err)
+	require.Equal(t, testUPID, upid)
+}
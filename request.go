@@ -1,12 +1,15 @@
 package proxmox
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/google/go-querystring/query"
 )
@@ -15,8 +18,26 @@ import (
 // Method should be a valid http request method.
 // Path should be an API path relative to the client's base URL.
 // Path should not have a preceding '/'
-// If specified, the value pointed to by opt is encoded into the query string of the URL.
+// If specified, the value pointed to by opt is encoded into the query string of the URL for GET and DELETE requests,
+// and into an application/x-www-form-urlencoded request body for POST and PUT requests.
+//
+// Options are encoded with github.com/google/go-querystring, so option structs should use `url` struct tags.
+// Proxmox booleans should use the "int" tag option to be encoded as 0 or 1, for example `url:"force,omitempty,int"`.
+// Proxmox lists, like the "delete" parameter used to remove properties from a config, should use the "comma" tag option
+// to be encoded as a single comma separated value, for example `url:"delete,omitempty,comma"`.
+// Parameters can also be given as url.Values or map[string]string.
 func (c *Client) NewRequest(method, path string, opt interface{}) (*http.Request, error) {
+	return c.NewRequestWithContext(context.Background(), method, path, opt)
+}
+
+// NewRequestWithContext creates a new request carrying the given context.
+// The context controls the entire lifetime of the request made with Do, including reading and decoding the response body.
+// See NewRequest for details on the other arguments.
+func (c *Client) NewRequestWithContext(ctx context.Context, method, path string, opt interface{}) (*http.Request, error) {
+	if ctx == nil {
+		return nil, errors.New("context must be non-nil")
+	}
+
 	u := *c.baseURL
 	unescaped, err := url.PathUnescape(path)
 	if err != nil {
@@ -27,42 +48,126 @@ func (c *Client) NewRequest(method, path string, opt interface{}) (*http.Request
 	u.RawPath = c.baseURL.Path + path
 	u.Path = c.baseURL.Path + unescaped
 
-	// Set query parameters if any are provided
-	if opt != nil {
-		q, err := query.Values(opt)
-		if err != nil {
-			return nil, err
-		}
-		u.RawQuery = q.Encode()
+	// Encode parameters if any are provided
+	values, err := encodeOptions(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	// POST and PUT parameters are sent in a form encoded body, all others in the query string
+	hasBody := method == http.MethodPost || method == http.MethodPut
+	var body io.Reader
+	if hasBody {
+		body = strings.NewReader(values.Encode())
+	} else if values != nil {
+		u.RawQuery = values.Encode()
 	}
 
 	// Create request
-	req, err := http.NewRequest(method, u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
 	if err != nil {
 		return nil, err
 	}
 
 	// Set request header if making a POST or PUT
-	if req.Method == http.MethodPost || req.Method == http.MethodPut {
-		req.Header.Set("Content-Type", "x-www-form-urlencoded")
+	if hasBody {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	}
 
 	return req, nil
 }
 
+// encodeOptions encodes request parameters given as an option struct, url.Values or map[string]string
+func encodeOptions(opt interface{}) (url.Values, error) {
+	switch o := opt.(type) {
+	case nil:
+		return nil, nil
+	case url.Values:
+		return o, nil
+	case map[string]string:
+		values := url.Values{}
+		for k, v := range o {
+			values.Set(k, v)
+		}
+		return values, nil
+	default:
+		return query.Values(opt)
+	}
+}
+
 // Do sends an API request. The response is stored in the value 'v' or returned as an error.
 // If v implements the io.Writer interface, the raw response body will be written to v, without json decoding it.
+// The response body is always read and closed before Do returns, so the returned response's body can't be read anymore.
+// Pass an io.Writer, like a bytes.Buffer, as v to get the raw body instead.
+// The request's context is honored while waiting for the rate limits, sending the request and reading the response body.
 func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
-	// Set auth header according to https://pve.proxmox.com/wiki/Proxmox_VE_API#Authentication
-	req.Header.Set("Authorization", fmt.Sprintf("PVEAPIToken=%s=%s", c.tokenID, c.token))
+	if c.instrumenter != nil {
+		ctx, end := c.instrumenter.StartOperation(req.Context(), c.operation(req))
+		req = req.WithContext(ctx)
+		resp, err := c.doCached(req, v)
+		end(resp, err)
+		return resp, err
+	}
+
+	return c.doCached(req, v)
+}
+
+// doAuthenticated authenticates and sends an API request, renewing a rejected ticket once
+func (c *Client) doAuthenticated(req *http.Request, v interface{}) (*http.Response, error) {
+	// Keep an unauthenticated copy around in case a rejected ticket requires sending the request again
+	orig := req.Clone(req.Context())
+
+	if err := c.authenticate(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req, v)
+
+	// A ticket can be rejected before it expires, for example when the cluster's auth key was rotated.
+	// Request a new ticket and try once more if the request body can be sent again.
+	if err != nil && c.usesTicketAuth() && resp != nil && resp.StatusCode == http.StatusUnauthorized {
+		if orig.Body != nil && orig.Body != http.NoBody {
+			if orig.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := orig.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			orig.Body = body
+		}
+
+		c.ticket.invalidate()
+		if err := c.authenticate(orig); err != nil {
+			return nil, err
+		}
+		resp, err = c.do(orig, v)
+	}
+
+	return resp, err
+}
+
+// do sends an already authenticated API request and decodes its response into v
+func (c *Client) do(req *http.Request, v interface{}) (resp *http.Response, err error) {
+	ctx := req.Context()
+
+	// Log the request once its response was read
+	start := time.Now()
+	var attempts int
+	defer func() { c.logRequest(req, resp, err, attempts, time.Since(start)) }()
 
 	// Do request
-	resp, err := c.client.Do(req)
+	resp, attempts, err = c.send(req)
 	if err != nil {
+		// Prefer the context's error if it was canceled or timed out
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, err
 	}
+	defer func() { _ = resp.Body.Close() }()
 
-	// Check for error API response and capture it as an error
+	// Check for error API response and capture it as an APIError
 	// 3xx codes get treated as errors, unclear if there's a valid reason for redirection here
 	if resp.StatusCode > 299 {
 		body, err := io.ReadAll(resp.Body)
@@ -70,7 +175,7 @@ func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
 			return nil, fmt.Errorf("error reading Proxmox response body: %v", err)
 		}
 
-		return resp, errors.New(string(body))
+		return resp, newAPIError(resp, body)
 	}
 
 	// Copy body into v
@@ -82,5 +187,12 @@ func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
 		}
 	}
 
+	// Surface cancellation that interrupted reading the body
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return resp, ctxErr
+		}
+	}
+
 	return resp, err
 }
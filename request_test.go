@@ -0,0 +1,110 @@
+package proxmox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoContextCanceled(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	release := make(chan struct{})
+	defer close(release)
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	r, _, err := client.Nodes.GetNodesWithContext(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Nil(t, r)
+}
+
+func TestNewRequestWithContext(t *testing.T) {
+	_, server, client := setup(t)
+	defer teardown(server)
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+
+	req, err := client.NewRequestWithContext(ctx, http.MethodGet, "nodes", nil)
+	require.NoError(t, err)
+	require.Equal(t, "value", req.Context().Value(key{}))
+	require.Equal(t, fmt.Sprintf("%s/api2/json/nodes", server.URL), req.URL.String())
+}
+
+func TestNewRequestFormBody(t *testing.T) {
+	_, server, client := setup(t)
+	defer teardown(server)
+
+	type options struct {
+		Name    string   `url:"name,omitempty"`
+		Force   *bool    `url:"force,omitempty,int"`
+		Skip    bool     `url:"skiplock,omitempty,int"`
+		Delete  []string `url:"delete,omitempty,comma"`
+		Cores   int      `url:"cores,omitempty"`
+		Ignored string   `url:"-"`
+	}
+	force := false
+	opt := &options{
+		Name:    "vm 1",
+		Force:   &force,
+		Skip:    true,
+		Delete:  []string{"net1", "scsi2"},
+		Cores:   4,
+		Ignored: "ignored",
+	}
+
+	for _, method := range []string{http.MethodPost, http.MethodPut} {
+		req, err := client.NewRequest(method, "nodes/srv1/qemu/100/config", opt)
+		require.NoError(t, err)
+		require.Equal(t, "application/x-www-form-urlencoded", req.Header.Get("Content-Type"))
+		require.Empty(t, req.URL.RawQuery)
+
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		require.Equal(t, "cores=4&delete=net1%2Cscsi2&force=0&name=vm+1&skiplock=1", string(body))
+		require.NotNil(t, req.GetBody)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		req, err := client.NewRequest(method, "nodes/srv1/qemu/100/config", opt)
+		require.NoError(t, err)
+		require.Empty(t, req.Header.Get("Content-Type"))
+		require.Nil(t, req.Body)
+		require.Equal(t, "cores=4&delete=net1%2Cscsi2&force=0&name=vm+1&skiplock=1", req.URL.RawQuery)
+	}
+}
+
+func TestDoClosesBody(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/version", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"data":{"release":"8.2","repoid":"faa83925","version":"8.2.4"}}`)
+	})
+
+	req, err := client.NewRequest(http.MethodGet, "version", nil)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	resp, err := client.Do(req, &buf)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data":{"release":"8.2","repoid":"faa83925","version":"8.2.4"}}`, buf.String())
+
+	// The body was consumed by Do
+	_, err = io.ReadAll(resp.Body)
+	require.Error(t, err)
+}
@@ -0,0 +1,175 @@
+package proxmox
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultRetryMaxAttempts is the default number of attempts made for a request, including the first
+	defaultRetryMaxAttempts = 4
+
+	// defaultRetryMinBackoff is the default wait before the first retry
+	defaultRetryMinBackoff = 250 * time.Millisecond
+
+	// defaultRetryMaxBackoff is the default longest wait between retries
+	defaultRetryMaxBackoff = 5 * time.Second
+)
+
+// RetryPolicy configures how the client retries requests that failed for transient reasons,
+// like a restarting pveproxy or a proxied node that can't be reached.
+// Zero values fall back to their defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts made for a request, including the first.
+	// Default: 4
+	MaxAttempts int
+
+	// MinBackoff is the wait before the first retry. It doubles after every retry, with jitter applied.
+	// Default: 250ms
+	MinBackoff time.Duration
+
+	// MaxBackoff is the longest wait between retries.
+	// Default: 5s
+	MaxBackoff time.Duration
+
+	// RetryMutating allows retrying POST, PUT and DELETE requests. By default only GET and HEAD requests are retried,
+	// since a mutating request may have taken effect even though its response was lost.
+	RetryMutating bool
+
+	// ShouldRetry decides whether an attempt should be retried. It receives either the response or the error of the attempt.
+	// 500 responses for missing resources, locked configurations and missing privileges are never retried.
+	// Default: DefaultShouldRetry
+	ShouldRetry func(resp *http.Response, err error) bool
+}
+
+// DefaultShouldRetry retries network errors, 502 Bad Gateway, 503 Service Unavailable, 504 Gateway Timeout and
+// the 59x statuses pveproxy uses when a proxied node can't be reached. It doesn't retry context cancellation,
+// or 500 Internal Server Error, which Proxmox responds with to errors that don't go away, like missing guests or invalid parameters.
+func DefaultShouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return !isContextError(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return resp.StatusCode >= 590 && resp.StatusCode <= 599
+	}
+}
+
+// WithRetry enables retrying requests that failed for transient reasons with exponential backoff.
+// Default: requests are attempted once
+func WithRetry(policy RetryPolicy) ClientOptionFunc {
+	return func(c *Client) error {
+		if policy.MaxAttempts <= 0 {
+			policy.MaxAttempts = defaultRetryMaxAttempts
+		}
+		if policy.MinBackoff <= 0 {
+			policy.MinBackoff = defaultRetryMinBackoff
+		}
+		if policy.MaxBackoff <= 0 {
+			policy.MaxBackoff = defaultRetryMaxBackoff
+		}
+		if policy.MaxBackoff < policy.MinBackoff {
+			policy.MaxBackoff = policy.MinBackoff
+		}
+		if policy.ShouldRetry == nil {
+			policy.ShouldRetry = DefaultShouldRetry
+		}
+		c.retry = &policy
+		return nil
+	}
+}
+
+// allows reports whether the policy permits retrying the request
+func (p *RetryPolicy) allows(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead && !p.RetryMutating {
+		return false
+	}
+
+	// The body must be replayable to send it again
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// permanentError reports whether a response is a 500 error that retrying won't fix, see IsNotFound, IsConfigLocked and IsPermissionDenied.
+// The response body is read to classify the error, and replaced so it can still be read.
+func permanentError(resp *http.Response) bool {
+	if resp == nil || resp.StatusCode != http.StatusInternalServerError {
+		return false
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	apiErr := newAPIError(resp, body)
+	return IsNotFound(apiErr) || IsConfigLocked(apiErr) || IsPermissionDenied(apiErr)
+}
+
+// backoff returns the wait before the given retry, counting from 1, with jitter applied
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	// Wait somewhere between half and all of the backoff so concurrent clients don't retry in lockstep
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// send sends a request, retrying it according to the client's retry policy, and returns the number of attempts made.
+// The response body of the returned response is left open for the caller.
+func (c *Client) send(req *http.Request) (*http.Response, int, error) {
+	if c.retry == nil || !c.retry.allows(req) {
+		resp, err := c.roundTrip(req)
+		return resp, 1, err
+	}
+
+	ctx := req.Context()
+	attemptReq := req
+	for attempt := 1; ; attempt++ {
+		// Retries are sent as a copy with a fresh body, since the caller's request must not be modified
+		if attempt > 1 {
+			attemptReq = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, attempt - 1, err
+				}
+				attemptReq.Body = body
+			}
+		}
+
+		resp, err := c.roundTrip(attemptReq)
+		if attempt >= c.retry.MaxAttempts || ctx.Err() != nil || !c.retry.ShouldRetry(resp, err) || permanentError(resp) {
+			return resp, attempt, err
+		}
+
+		// Discard the failed attempt's response so its connection can be reused
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		wait := c.retry.backoff(attempt)
+		c.logRetry(req, resp, err, attempt, wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attempt, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
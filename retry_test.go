@@ -0,0 +1,198 @@
+package proxmox
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetry(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithRetry(RetryPolicy{MinBackoff: time.Millisecond})(client))
+
+	var attempts int32
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&attempts, 1) {
+		case 1:
+			w.WriteHeader(595)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, err := fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+			if err != nil {
+				return
+			}
+		}
+	})
+
+	r, _, err := client.Nodes.GetNodes()
+	require.NoError(t, err)
+	require.Len(t, r.Data, 3)
+	require.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithRetry(RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond})(client))
+
+	var attempts int32
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(596)
+	})
+
+	_, resp, err := client.Nodes.GetNodes()
+	require.Error(t, err)
+	require.Equal(t, 596, resp.StatusCode)
+	require.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+}
+
+func TestRetryNotFound(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithRetry(RetryPolicy{MinBackoff: time.Millisecond})(client))
+
+	var attempts int32
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	_, _, err := client.Nodes.GetNodes()
+	require.Error(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+}
+
+func TestRetryInternalServerError(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	var attempts int32
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/999/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = fmt.Fprint(w, `{"data":null,"message":"Configuration file 'nodes/srv1/qemu-server/999.conf' does not exist\n"}`)
+	})
+
+	// 500 responses aren't retried by default
+	require.NoError(t, WithRetry(RetryPolicy{MinBackoff: time.Millisecond})(client))
+	_, _, err := client.Nodes.GetQemuSnapshots("srv1", 999)
+	require.True(t, IsNotFound(err))
+	require.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+
+	// Not even by policies retrying every 500, since the guest won't appear by retrying
+	atomic.StoreInt32(&attempts, 0)
+	require.NoError(t, WithRetry(RetryPolicy{
+		MinBackoff:  time.Millisecond,
+		ShouldRetry: func(resp *http.Response, err error) bool { return err != nil || resp.StatusCode >= 500 },
+	})(client))
+	_, _, err = client.Nodes.GetQemuSnapshots("srv1", 999)
+	require.True(t, IsNotFound(err))
+	require.Contains(t, err.Error(), "does not exist")
+	require.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+}
+
+func TestRetryMutating(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	var attempts int32
+	mux.HandleFunc("/api2/json/nodes/srv1/qemu/100/status/start", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil || string(body) != "skiplock=1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, err = fmt.Fprint(w, `{"data":null}`)
+		if err != nil {
+			return
+		}
+	})
+
+	type options struct {
+		SkipLock bool `url:"skiplock,int"`
+	}
+
+	// Mutating requests aren't retried by default
+	require.NoError(t, WithRetry(RetryPolicy{MinBackoff: time.Millisecond})(client))
+	req, err := client.NewRequest(http.MethodPost, "nodes/srv1/qemu/100/status/start", &options{SkipLock: true})
+	require.NoError(t, err)
+	_, err = client.Do(req, nil)
+	require.Error(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+
+	// Retried with the same body once opted in
+	atomic.StoreInt32(&attempts, 0)
+	require.NoError(t, WithRetry(RetryPolicy{MinBackoff: time.Millisecond, RetryMutating: true})(client))
+	req, err = client.NewRequest(http.MethodPost, "nodes/srv1/qemu/100/status/start", &options{SkipLock: true})
+	require.NoError(t, err)
+	body := req.Body
+	_, err = client.Do(req, nil)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+
+	// The retry was sent without replacing the body of the caller's request
+	require.True(t, req.Body == body)
+}
+
+func TestRetryNetworkError(t *testing.T) {
+	// Nothing listens on this server once it's closed
+	_, server, client := setup(t)
+	teardown(server)
+	require.NoError(t, WithRetry(RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond})(client))
+
+	var attempts int32
+	client.client.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&attempts, 1)
+		return (&http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}).RoundTrip(req)
+	})
+
+	_, _, err := client.Nodes.GetNodes()
+	require.Error(t, err)
+	require.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestRetryContextCanceled(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+	require.NoError(t, WithRetry(RetryPolicy{MaxAttempts: 100, MinBackoff: time.Second})(client))
+
+	mux.HandleFunc("/api2/json/nodes", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, _, err := client.Nodes.GetNodesWithContext(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := &RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for retry, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 5: time.Second, 20: time.Second} {
+		d := p.backoff(retry)
+		require.GreaterOrEqual(t, d, max/2)
+		require.LessOrEqual(t, d, max)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,310 @@
+package proxmox
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultTaskPollInterval is the initial interval between task status requests in TaskService.Wait
+	defaultTaskPollInterval = 500 * time.Millisecond
+
+	// defaultTaskMaxPollInterval is the longest interval between task status requests in TaskService.Wait
+	defaultTaskMaxPollInterval = 5 * time.Second
+
+	// defaultTaskLogLines is the number of task log lines included in a TaskError
+	defaultTaskLogLines = 10
+)
+
+// TaskService is the service that encapsulates task API methods
+type TaskService struct {
+	client *Client
+}
+
+// UPID is a parsed Proxmox unique task identifier, like "UPID:srv1:0012A3B4:05F6E7D8:65A1B2C3:qmstart:100:root@pam:"
+type UPID struct {
+	Node      string
+	PID       int
+	PStart    int
+	StartTime time.Time
+	Type      string
+	ID        string
+	User      string
+}
+
+// ParseUPID parses a Proxmox unique task identifier
+func ParseUPID(s string) (*UPID, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 9 || parts[0] != "UPID" || parts[8] != "" {
+		return nil, fmt.Errorf("invalid UPID %q", s)
+	}
+
+	pid, err := strconv.ParseInt(parts[2], 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid UPID %q: pid: %w", s, err)
+	}
+	pstart, err := strconv.ParseInt(parts[3], 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid UPID %q: pstart: %w", s, err)
+	}
+	start, err := strconv.ParseInt(parts[4], 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid UPID %q: starttime: %w", s, err)
+	}
+	if parts[1] == "" || parts[5] == "" || parts[7] == "" {
+		return nil, fmt.Errorf("invalid UPID %q: missing node, type or user", s)
+	}
+
+	return &UPID{
+		Node:      parts[1],
+		PID:       int(pid),
+		PStart:    int(pstart),
+		StartTime: time.Unix(start, 0),
+		Type:      parts[5],
+		ID:        parts[6],
+		User:      parts[7],
+	}, nil
+}
+
+// String returns the UPID in the format Proxmox uses
+func (u *UPID) String() string {
+	return fmt.Sprintf("UPID:%s:%08X:%08X:%08X:%s:%s:%s:", u.Node, u.PID, u.PStart, u.StartTime.Unix(), u.Type, u.ID, u.User)
+}
+
+// TaskResponse contains the response of endpoints that start a task, like starting a VM.
+// Wait for the task to finish with TaskService.Wait.
+type TaskResponse struct {
+	Data string `json:"data"` // UPID of the started task
+}
+
+// GetTaskStatusResponse contains the response for the /nodes/{node}/tasks/{upid}/status endpoint
+type GetTaskStatusResponse struct {
+	Data GetTaskStatusData `json:"data"`
+}
+
+// GetTaskStatusData contains the status of a task from a GetTaskStatus response
+type GetTaskStatusData struct {
+	ID         string  `json:"id"`
+	Node       string  `json:"node"`
+	PID        int     `json:"pid"`
+	PStart     int     `json:"pstart"`
+	StartTime  int     `json:"starttime"`
+	Status     string  `json:"status"` // Either "running" or "stopped"
+	Type       string  `json:"type"`
+	UPID       string  `json:"upid"`
+	User       string  `json:"user"`
+	ExitStatus *string `json:"exitstatus,omitempty"` // Only set once the task stopped
+	TokenID    *string `json:"tokenid,omitempty"`
+}
+
+// IsRunning reports whether the task is still running
+func (d *GetTaskStatusData) IsRunning() bool {
+	return d.Status == "running"
+}
+
+// IsSuccessful reports whether the task stopped without errors. Tasks that completed with warnings count as successful.
+func (d *GetTaskStatusData) IsSuccessful() bool {
+	if d.IsRunning() || d.ExitStatus == nil {
+		return false
+	}
+	return *d.ExitStatus == "OK" || strings.HasPrefix(*d.ExitStatus, "WARNINGS")
+}
+
+// GetTaskStatus makes a GET request to the /nodes/{node}/tasks/{upid}/status endpoint
+// The node is taken from the UPID.
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/tasks/{upid}/status
+func (s *TaskService) GetTaskStatus(upid string) (*GetTaskStatusResponse, *http.Response, error) {
+	return s.GetTaskStatusWithContext(context.Background(), upid)
+}
+
+// GetTaskStatusWithContext is like GetTaskStatus but uses the given context for the request
+func (s *TaskService) GetTaskStatusWithContext(ctx context.Context, upid string) (*GetTaskStatusResponse, *http.Response, error) {
+	u, err := taskPath(upid, "status")
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "TaskService.GetTaskStatus"), http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := new(GetTaskStatusResponse)
+	resp, err := s.client.Do(req, d)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return d, resp, nil
+}
+
+// GetTaskLogOptions contains the optional parameters for the /nodes/{node}/tasks/{upid}/log endpoint
+type GetTaskLogOptions struct {
+	Start *int `url:"start,omitempty"` // Line number to start at, counting from 0
+	Limit *int `url:"limit,omitempty"` // Maximum number of lines to return
+}
+
+// GetTaskLogResponse contains the response for the /nodes/{node}/tasks/{upid}/log endpoint
+type GetTaskLogResponse struct {
+	Data  []GetTaskLogData `json:"data"`
+	Total int              `json:"total"` // Total number of lines in the task log
+}
+
+// GetTaskLogData contains one line of a task log from a GetTaskLog response
+type GetTaskLogData struct {
+	N int    `json:"n"` // Line number
+	T string `json:"t"` // Line text
+}
+
+// GetTaskLog makes a GET request to the /nodes/{node}/tasks/{upid}/log endpoint
+// The node is taken from the UPID.
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/nodes/{node}/tasks/{upid}/log
+func (s *TaskService) GetTaskLog(upid string, opt *GetTaskLogOptions) (*GetTaskLogResponse, *http.Response, error) {
+	return s.GetTaskLogWithContext(context.Background(), upid, opt)
+}
+
+// GetTaskLogWithContext is like GetTaskLog but uses the given context for the request
+func (s *TaskService) GetTaskLogWithContext(ctx context.Context, upid string, opt *GetTaskLogOptions) (*GetTaskLogResponse, *http.Response, error) {
+	u, err := taskPath(upid, "log")
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "TaskService.GetTaskLog"), http.MethodGet, u, opt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := new(GetTaskLogResponse)
+	resp, err := s.client.Do(req, d)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return d, resp, nil
+}
+
+// taskPath returns the API path of a task endpoint, using the node from the UPID
+func taskPath(upid, endpoint string) (string, error) {
+	u, err := ParseUPID(upid)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("nodes/%s/tasks/%s/%s", u.Node, url.PathEscape(upid), endpoint), nil
+}
+
+// WaitOptions contains the optional settings for TaskService.Wait
+type WaitOptions struct {
+	// PollInterval is the initial interval between task status requests. It doubles after every request.
+	// Default: 500ms
+	PollInterval time.Duration
+
+	// MaxPollInterval is the longest interval between task status requests.
+	// Default: 5s
+	MaxPollInterval time.Duration
+
+	// LogLines is the number of lines from the end of the task log included in the TaskError of a failed task.
+	// Default: 10
+	LogLines int
+}
+
+// TaskError is returned by TaskService.Wait when a task stopped with an error
+type TaskError struct {
+	// UPID is the identifier of the failed task
+	UPID string
+
+	// ExitStatus is the exit status of the task, which holds the error message
+	ExitStatus string
+
+	// Log contains the last lines of the task log
+	Log []string
+}
+
+// Error implements the error interface for TaskError
+func (e *TaskError) Error() string {
+	msg := fmt.Sprintf("task %s failed: %s", e.UPID, e.ExitStatus)
+	if len(e.Log) > 0 {
+		msg += "\n" + strings.Join(e.Log, "\n")
+	}
+	return msg
+}
+
+// Wait polls the status of a task until it stopped, backing off between requests.
+// It returns the final task status, or a *TaskError if the task did not complete successfully.
+func (s *TaskService) Wait(upid string, opt *WaitOptions) (*GetTaskStatusData, error) {
+	return s.WaitWithContext(context.Background(), upid, opt)
+}
+
+// WaitWithContext is like Wait but stops waiting when the given context is done
+func (s *TaskService) WaitWithContext(ctx context.Context, upid string, opt *WaitOptions) (*GetTaskStatusData, error) {
+	interval, maxInterval, logLines := defaultTaskPollInterval, defaultTaskMaxPollInterval, defaultTaskLogLines
+	if opt != nil {
+		if opt.PollInterval > 0 {
+			interval = opt.PollInterval
+		}
+		if opt.MaxPollInterval > 0 {
+			maxInterval = opt.MaxPollInterval
+		}
+		if opt.LogLines > 0 {
+			logLines = opt.LogLines
+		}
+	}
+
+	for {
+		status, _, err := s.GetTaskStatusWithContext(ctx, upid)
+		if err != nil {
+			return nil, err
+		}
+
+		if !status.Data.IsRunning() {
+			if status.Data.IsSuccessful() {
+				return &status.Data, nil
+			}
+			return &status.Data, s.taskError(ctx, upid, &status.Data, logLines)
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		interval *= 2
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
+// taskError builds a TaskError for a failed task, including the last lines of its log
+func (s *TaskService) taskError(ctx context.Context, upid string, status *GetTaskStatusData, logLines int) *TaskError {
+	e := &TaskError{UPID: upid}
+	if status.ExitStatus != nil {
+		e.ExitStatus = *status.ExitStatus
+	}
+
+	// Find the total number of log lines first, then request only the tail. The log is best effort.
+	limit := 1
+	head, _, err := s.GetTaskLogWithContext(ctx, upid, &GetTaskLogOptions{Limit: &limit})
+	if err != nil {
+		return e
+	}
+	start := head.Total - logLines
+	if start < 0 {
+		start = 0
+	}
+	tail, _, err := s.GetTaskLogWithContext(ctx, upid, &GetTaskLogOptions{Start: &start, Limit: &logLines})
+	if err != nil {
+		return e
+	}
+	for _, line := range tail.Data {
+		e.Log = append(e.Log, line.T)
+	}
+
+	return e
+}
@@ -0,0 +1,192 @@
+package proxmox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testUPID = "UPID:srv1:0012A3B4:05FC7468:65A1B6C3:qmstart:100:root@pam:"
+
+func TestParseUPID(t *testing.T) {
+	u, err := ParseUPID(testUPID)
+	require.NoError(t, err)
+	require.Equal(t, UPID{
+		Node:      "srv1",
+		PID:       1221556,
+		PStart:    100430952,
+		StartTime: time.Unix(1705096899, 0),
+		Type:      "qmstart",
+		ID:        "100",
+		User:      "root@pam",
+	}, *u)
+	require.Equal(t, testUPID, u.String())
+
+	// Tasks without an ID and with API token users
+	u, err = ParseUPID("UPID:srv1:0012A3B4:05FC7468:65A1B6C3:aptupdate::root@pam!automation:")
+	require.NoError(t, err)
+	require.Equal(t, "", u.ID)
+	require.Equal(t, "root@pam!automation", u.User)
+
+	for _, s := range []string{
+		"",
+		"UPID:srv1:0012A3B4:05FC7468:65A1B6C3:qmstart:100:root@pam",
+		"TASK:srv1:0012A3B4:05FC7468:65A1B6C3:qmstart:100:root@pam:",
+		"UPID:srv1:notHex:05FC7468:65A1B6C3:qmstart:100:root@pam:",
+		"UPID::0012A3B4:05FC7468:65A1B6C3:qmstart:100:root@pam:",
+	} {
+		_, err := ParseUPID(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestGetTaskStatus(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/tasks/"+testUPID+"/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err := fmt.Fprint(w, fixture("tasks/get_task_status.json"))
+		if err != nil {
+			return
+		}
+	})
+
+	want := GetTaskStatusResponse{
+		Data: GetTaskStatusData{
+			ID:         "100",
+			Node:       "srv1",
+			PID:        1221556,
+			PStart:     100430952,
+			StartTime:  1705096899,
+			Status:     "stopped",
+			Type:       "qmstart",
+			UPID:       testUPID,
+			User:       "root@pam",
+			ExitStatus: testStr("OK"),
+		},
+	}
+
+	r, resp, err := client.Tasks.GetTaskStatus(testUPID)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.Equal(t, want, *r)
+	require.True(t, r.Data.IsSuccessful())
+}
+
+func TestGetTaskLog(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/tasks/"+testUPID+"/log", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("start") != "1" || r.URL.Query().Get("limit") != "2" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err := fmt.Fprint(w, fixture("tasks/get_task_log.json"))
+		if err != nil {
+			return
+		}
+	})
+
+	want := GetTaskLogResponse{
+		Data: []GetTaskLogData{
+			{N: 1, T: "generating cloud-init ISO"},
+			{N: 2, T: "TASK OK"},
+		},
+		Total: 2,
+	}
+
+	r, resp, err := client.Tasks.GetTaskLog(testUPID, &GetTaskLogOptions{Start: testInt(1), Limit: testInt(2)})
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.Equal(t, want, *r)
+}
+
+func TestWait(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	var polls int32
+	mux.HandleFunc("/api2/json/nodes/srv1/tasks/"+testUPID+"/status", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&polls, 1) < 3 {
+			_, err := fmt.Fprintf(w, `{"data":{"status":"running","upid":%q,"node":"srv1"}}`, testUPID)
+			if err != nil {
+				return
+			}
+			return
+		}
+		_, err := fmt.Fprint(w, fixture("tasks/get_task_status.json"))
+		if err != nil {
+			return
+		}
+	})
+
+	status, err := client.Tasks.Wait(testUPID, &WaitOptions{PollInterval: time.Millisecond})
+	require.NoError(t, err)
+	require.Equal(t, "OK", *status.ExitStatus)
+	require.Equal(t, int32(3), atomic.LoadInt32(&polls))
+}
+
+func TestWaitTaskFailed(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/tasks/"+testUPID+"/status", func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprintf(w, `{"data":{"status":"stopped","exitstatus":"can't lock file '/var/lock/qemu-server/lock-100.conf' - got timeout","upid":%q,"node":"srv1"}}`, testUPID)
+		if err != nil {
+			return
+		}
+	})
+	mux.HandleFunc("/api2/json/nodes/srv1/tasks/"+testUPID+"/log", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("start") == "" {
+			_, err := fmt.Fprint(w, `{"data":[{"n":1,"t":"line 1"}],"total":3}`)
+			if err != nil {
+				return
+			}
+			return
+		}
+		if r.URL.Query().Get("start") != "1" || r.URL.Query().Get("limit") != "2" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, err := fmt.Fprint(w, `{"data":[{"n":2,"t":"line 2"},{"n":3,"t":"TASK ERROR: can't lock file"}],"total":3}`)
+		if err != nil {
+			return
+		}
+	})
+
+	_, err := client.Tasks.Wait(testUPID, &WaitOptions{LogLines: 2})
+	var taskErr *TaskError
+	require.True(t, errors.As(err, &taskErr))
+	require.Equal(t, testUPID, taskErr.UPID)
+	require.Equal(t, "can't lock file '/var/lock/qemu-server/lock-100.conf' - got timeout", taskErr.ExitStatus)
+	require.Equal(t, []string{"line 2", "TASK ERROR: can't lock file"}, taskErr.Log)
+}
+
+func TestWaitContextCanceled(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/nodes/srv1/tasks/"+testUPID+"/status", func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprintf(w, `{"data":{"status":"running","upid":%q,"node":"srv1"}}`, testUPID)
+		if err != nil {
+			return
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Tasks.WaitWithContext(ctx, testUPID, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
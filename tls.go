@@ -0,0 +1,306 @@
+package proxmox
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrFingerprintMismatch is returned when the API server presents a certificate whose fingerprint isn't pinned
+var ErrFingerprintMismatch = errors.New("TLS certificate fingerprint is not pinned")
+
+// pinSet holds the SHA-256 certificate fingerprints a client trusts
+type pinSet struct {
+	mu           sync.Mutex
+	fingerprints map[string]bool
+
+	// tofuPath is the file fingerprints are stored in when trusting on first use
+	tofuPath string
+}
+
+// WithTLSFingerprint pins the SHA-256 fingerprint of the certificate pveproxy presents, as shown by GetNodes or
+// GetNodeCertificatesInfo, e.g. "AB:CD:...". Any certificate with a pinned fingerprint is trusted, self-signed or not,
+// and every other certificate is rejected. Fingerprints may be given with or without colons.
+// This is a safe alternative to disabling TLS verification with InsecureSkipVerify.
+// The option can be combined with WithHTTPClient, whose client must use an *http.Transport.
+func WithTLSFingerprint(fingerprints ...string) ClientOptionFunc {
+	return func(c *Client) error {
+		if len(fingerprints) == 0 {
+			return errors.New("at least one TLS fingerprint is required")
+		}
+		if c.pins == nil {
+			c.pins = &pinSet{fingerprints: map[string]bool{}}
+		}
+		for _, fp := range fingerprints {
+			normalized, err := normalizeFingerprint(fp)
+			if err != nil {
+				return err
+			}
+			c.pins.fingerprints[normalized] = true
+		}
+		return nil
+	}
+}
+
+// WithTrustOnFirstUse pins the TLS fingerprints stored in the file at path. If the file doesn't exist or is empty,
+// the certificate the API server presents on the first connection is trusted, and its fingerprint is stored in the file.
+// Later connections only trust the stored fingerprints, as SSH does with known hosts.
+func WithTrustOnFirstUse(path string) ClientOptionFunc {
+	return func(c *Client) error {
+		if c.pins == nil {
+			c.pins = &pinSet{fingerprints: map[string]bool{}}
+		}
+		c.pins.tofuPath = path
+
+		b, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading TLS fingerprints: %w", err)
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			fp, err := normalizeFingerprint(line)
+			if err != nil {
+				return fmt.Errorf("error reading TLS fingerprints from %s: %w", path, err)
+			}
+			c.pins.fingerprints[fp] = true
+		}
+		return nil
+	}
+}
+
+// FetchTLSFingerprint connects to the API server at urlStr without verifying its certificate,
+// and returns the SHA-256 fingerprint of the certificate it presents. The port defaults to 8006.
+// Confirm the fingerprint out of band, e.g. in the web UI, before pinning it with WithTLSFingerprint.
+func FetchTLSFingerprint(ctx context.Context, urlStr string) (string, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return "", err
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("no host in URL %q", urlStr)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "8006"
+	}
+
+	dialer := &tls.Dialer{Config: &tls.Config{InsecureSkipVerify: true}}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(u.Hostname(), port))
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = conn.Close() }()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", errors.New("API server presented no TLS certificate")
+	}
+	return certificateFingerprint(certs[0]), nil
+}
+
+// TLSFingerprints returns the pinned TLS fingerprints in sorted order, or nil if fingerprints aren't pinned
+func (c *Client) TLSFingerprints() []string {
+	if c.pins == nil {
+		return nil
+	}
+	return c.pins.list()
+}
+
+// RefreshTLSFingerprints adds the certificate fingerprints of every cluster node from GetNodes to the pinned fingerprints,
+// so the client can fail over to nodes it didn't connect to before. The node list is requested over a connection verified
+// with the current pins. When trusting on first use, the fingerprints are stored too.
+func (c *Client) RefreshTLSFingerprints(ctx context.Context) error {
+	if c.pins == nil {
+		return errors.New("TLS fingerprints aren't pinned, set them with WithTLSFingerprint or WithTrustOnFirstUse first")
+	}
+
+	nodes, _, err := c.Nodes.GetNodesWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("error refreshing TLS fingerprints: %w", err)
+	}
+
+	var fingerprints []string
+	for _, n := range nodes.Data {
+		if n.SSLFingerprint == "" {
+			continue
+		}
+		fp, err := normalizeFingerprint(n.SSLFingerprint)
+		if err != nil {
+			return fmt.Errorf("error refreshing TLS fingerprints of node %s: %w", n.Node, err)
+		}
+		fingerprints = append(fingerprints, fp)
+	}
+
+	return c.pins.add(fingerprints...)
+}
+
+// WithCAFile trusts the certificate authorities in the PEM file at path, in addition to the system roots,
+// e.g. /etc/pve/pve-root-ca.pem of the cluster.
+// The option can be combined with WithHTTPClient, whose client must use an *http.Transport.
+func WithCAFile(path string) ClientOptionFunc {
+	return func(c *Client) error {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading CA file: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return fmt.Errorf("no PEM certificates found in CA file %s", path)
+		}
+		c.rootCAs = pool
+		return nil
+	}
+}
+
+// WithInsecureSkipVerify disables TLS certificate verification. Prefer WithTLSFingerprint or WithCAFile,
+// this should only be used for testing. The option can be combined with WithHTTPClient, whose client must use an *http.Transport.
+func WithInsecureSkipVerify() ClientOptionFunc {
+	return func(c *Client) error {
+		c.insecure = true
+		return nil
+	}
+}
+
+// configureTLS applies the TLS options of the client to its HTTP client.
+// The HTTP client and its transport are copied, so a client passed to WithHTTPClient isn't modified.
+func (c *Client) configureTLS() error {
+	if c.rootCAs == nil && !c.insecure && c.pins == nil {
+		return nil
+	}
+
+	var transport *http.Transport
+	switch t := c.client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return fmt.Errorf("TLS options require an *http.Transport, the HTTP client uses %T", t)
+	}
+
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	if c.rootCAs != nil {
+		transport.TLSClientConfig.RootCAs = c.rootCAs
+	}
+	if c.insecure {
+		transport.TLSClientConfig.InsecureSkipVerify = true
+	}
+	if c.pins != nil {
+		// The certificate chain isn't verified, the pinned fingerprint is checked in VerifyConnection instead
+		transport.TLSClientConfig.InsecureSkipVerify = true
+		transport.TLSClientConfig.VerifyConnection = c.pins.verify
+	}
+
+	client := *c.client
+	client.Transport = transport
+	c.client = &client
+
+	return nil
+}
+
+// verify checks that the leaf certificate of a connection has a pinned fingerprint,
+// trusting it on first use if no fingerprints are pinned yet
+func (p *pinSet) verify(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("API server presented no TLS certificate")
+	}
+	fp := certificateFingerprint(cs.PeerCertificates[0])
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.fingerprints[fp] {
+		return nil
+	}
+	// Decide under the lock, so only one certificate is ever trusted on first use
+	if len(p.fingerprints) == 0 && p.tofuPath != "" {
+		return p.addLocked(fp)
+	}
+	return fmt.Errorf("%w: %s", ErrFingerprintMismatch, fp)
+}
+
+// add pins fingerprints, storing them when trusting on first use
+func (p *pinSet) add(fingerprints ...string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.addLocked(fingerprints...)
+}
+
+// addLocked is like add, but the caller must hold p.mu
+func (p *pinSet) addLocked(fingerprints ...string) error {
+	for _, fp := range fingerprints {
+		p.fingerprints[fp] = true
+	}
+	if p.tofuPath == "" {
+		return nil
+	}
+
+	if err := os.WriteFile(p.tofuPath, []byte(strings.Join(p.sorted(), "\n")+"\n"), 0o600); err != nil {
+		return fmt.Errorf("error storing TLS fingerprints: %w", err)
+	}
+	return nil
+}
+
+// list returns the pinned fingerprints in sorted order
+func (p *pinSet) list() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sorted()
+}
+
+// sorted returns the pinned fingerprints in sorted order. The caller must hold p.mu.
+func (p *pinSet) sorted() []string {
+	fps := make([]string, 0, len(p.fingerprints))
+	for fp := range p.fingerprints {
+		fps = append(fps, fp)
+	}
+	sort.Strings(fps)
+	return fps
+}
+
+// certificateFingerprint returns the SHA-256 fingerprint of a certificate in the format Proxmox VE uses
+func certificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return formatFingerprint(sum[:])
+}
+
+// normalizeFingerprint parses a SHA-256 fingerprint with or without colons and returns it in the format Proxmox VE uses
+func normalizeFingerprint(fp string) (string, error) {
+	b, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(fp), ":", ""))
+	if err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("invalid SHA-256 fingerprint %q", fp)
+	}
+	return formatFingerprint(b), nil
+}
+
+// formatFingerprint formats a fingerprint as colon separated upper case hex bytes
+func formatFingerprint(b []byte) string {
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02X", v)
+	}
+	return strings.Join(parts, ":")
+}
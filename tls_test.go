@@ -0,0 +1,220 @@
+package proxmox
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTLSServer starts a TLS server with its own self-signed certificate, since httptest servers share theirs.
+// The certificate is valid for 127.0.0.1, or for the given DNS names instead.
+func newTLSServer(t *testing.T, handler http.Handler, dnsNames ...string) *httptest.Server {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "pve.example.com"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	if len(dnsNames) > 0 {
+		template.IPAddresses, template.DNSNames = nil, dnsNames
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(handler)
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	server.StartTLS()
+	return server
+}
+
+func TestNormalizeFingerprint(t *testing.T) {
+	want := strings.TrimSuffix(strings.Repeat("AB:", 32), ":")
+
+	fp, err := normalizeFingerprint(want)
+	require.NoError(t, err)
+	require.Equal(t, want, fp)
+
+	fp, err = normalizeFingerprint(strings.Repeat("ab", 32))
+	require.NoError(t, err)
+	require.Equal(t, want, fp)
+
+	_, err = normalizeFingerprint("AB:CD")
+	require.Error(t, err)
+	_, err = normalizeFingerprint(strings.Repeat("zz", 32))
+	require.Error(t, err)
+}
+
+func TestWithTLSFingerprint(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+	}))
+	defer server.Close()
+	fp := certificateFingerprint(server.Certificate())
+
+	_, err := NewClient("test-token-id", "test-token", WithTLSFingerprint())
+	require.Error(t, err)
+	_, err = NewClient("test-token-id", "test-token", WithTLSFingerprint("AB:CD"))
+	require.Error(t, err)
+
+	// A pinned self-signed certificate is trusted, the fingerprint may be given without colons
+	c, err := NewClient("test-token-id", "test-token", WithBaseURL(server.URL), WithTLSFingerprint(strings.ReplaceAll(strings.ToLower(fp), ":", "")))
+	require.NoError(t, err)
+	require.Equal(t, []string{fp}, c.TLSFingerprints())
+	_, _, err = c.Nodes.GetNodes()
+	require.NoError(t, err)
+
+	// Other certificates are rejected
+	c, err = NewClient("test-token-id", "test-token", WithBaseURL(server.URL), WithTLSFingerprint(strings.Repeat("00", 32)))
+	require.NoError(t, err)
+	_, _, err = c.Nodes.GetNodes()
+	require.ErrorIs(t, err, ErrFingerprintMismatch)
+
+	// The HTTP client passed with WithHTTPClient isn't modified
+	httpClient := &http.Client{Transport: &http.Transport{}}
+	c, err = NewClient("test-token-id", "test-token", WithBaseURL(server.URL), WithHTTPClient(httpClient), WithTLSFingerprint(fp))
+	require.NoError(t, err)
+	_, _, err = c.Nodes.GetNodes()
+	require.NoError(t, err)
+	if tlsConfig := httpClient.Transport.(*http.Transport).TLSClientConfig; tlsConfig != nil {
+		require.False(t, tlsConfig.InsecureSkipVerify)
+		require.Nil(t, tlsConfig.VerifyConnection)
+	}
+
+	// Pinning needs an *http.Transport
+	_, err = NewClient("test-token-id", "test-token", WithHTTPClient(&http.Client{Transport: RoundTripperFunc(http.DefaultTransport.RoundTrip)}), WithTLSFingerprint(fp))
+	require.Error(t, err)
+
+	// Clients without pinned fingerprints verify certificates as usual
+	c, err = NewClient("test-token-id", "test-token", WithBaseURL(server.URL))
+	require.NoError(t, err)
+	require.Nil(t, c.TLSFingerprints())
+	_, _, err = c.Nodes.GetNodes()
+	require.Error(t, err)
+}
+
+func TestWithTrustOnFirstUse(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+	})
+	first := newTLSServer(t, handler)
+	defer first.Close()
+	second := newTLSServer(t, handler)
+	defer second.Close()
+	path := filepath.Join(t.TempDir(), "fingerprints")
+
+	// The first certificate is trusted and stored
+	c, err := NewClient("test-token-id", "test-token", WithBaseURL(first.URL), WithTrustOnFirstUse(path))
+	require.NoError(t, err)
+	require.Empty(t, c.TLSFingerprints())
+	_, _, err = c.Nodes.GetNodes()
+	require.NoError(t, err)
+	fp := certificateFingerprint(first.Certificate())
+	require.Equal(t, []string{fp}, c.TLSFingerprints())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, fp+"\n", string(b))
+
+	// Later clients only trust the stored certificate
+	c, err = NewClient("test-token-id", "test-token", WithBaseURL(first.URL), WithTrustOnFirstUse(path))
+	require.NoError(t, err)
+	_, _, err = c.Nodes.GetNodes()
+	require.NoError(t, err)
+
+	c, err = NewClient("test-token-id", "test-token", WithBaseURL(second.URL), WithTrustOnFirstUse(path))
+	require.NoError(t, err)
+	_, _, err = c.Nodes.GetNodes()
+	require.ErrorIs(t, err, ErrFingerprintMismatch)
+
+	// Invalid files are rejected
+	require.NoError(t, os.WriteFile(path, []byte("not a fingerprint\n"), 0o600))
+	_, err = NewClient("test-token-id", "test-token", WithTrustOnFirstUse(path))
+	require.Error(t, err)
+}
+
+func TestClient_RefreshTLSFingerprints(t *testing.T) {
+	second := newTLSServer(t, http.NotFoundHandler())
+	defer second.Close()
+	secondFP := certificateFingerprint(second.Certificate())
+
+	first := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"data":[{"node":"srv1","ssl_fingerprint":"%s"},{"node":"srv2","ssl_fingerprint":""}]}`, secondFP)
+	}))
+	defer first.Close()
+	firstFP := certificateFingerprint(first.Certificate())
+
+	c, err := NewClient("test-token-id", "test-token", WithBaseURL(first.URL))
+	require.NoError(t, err)
+	require.Error(t, c.RefreshTLSFingerprints(context.Background()))
+
+	c, err = NewClient("test-token-id", "test-token", WithBaseURLs(first.URL, second.URL), WithTLSFingerprint(firstFP))
+	require.NoError(t, err)
+	require.NoError(t, c.RefreshTLSFingerprints(context.Background()))
+
+	want := []string{firstFP, secondFP}
+	if want[0] > want[1] {
+		want[0], want[1] = want[1], want[0]
+	}
+	require.Equal(t, want, c.TLSFingerprints())
+}
+
+func TestFetchTLSFingerprint(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	fp, err := FetchTLSFingerprint(context.Background(), server.URL)
+	require.NoError(t, err)
+	require.Equal(t, certificateFingerprint(server.Certificate()), fp)
+
+	_, err = FetchTLSFingerprint(context.Background(), "/no/host")
+	require.Error(t, err)
+}
+
+func TestWithCAFile(t *testing.T) {
+	server := newTLSServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, fixture("nodes/get_nodes.json"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+
+	c, err := NewClient("test-token-id", "test-token", WithBaseURL(server.URL), WithCAFile(caFile))
+	require.NoError(t, err)
+	_, _, err = c.Nodes.GetNodes()
+	require.NoError(t, err)
+
+	c, err = NewClient("test-token-id", "test-token", WithBaseURL(server.URL), WithInsecureSkipVerify())
+	require.NoError(t, err)
+	_, _, err = c.Nodes.GetNodes()
+	require.NoError(t, err)
+
+	_, err = NewClient("test-token-id", "test-token", WithCAFile(filepath.Join(dir, "missing.pem")))
+	require.Error(t, err)
+
+	notPEM := filepath.Join(dir, "not.pem")
+	require.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+	_, err = NewClient("test-token-id", "test-token", WithCAFile(notPEM))
+	require.Error(t, err)
+}
@@ -64,6 +64,13 @@ type CephHealthStatus struct {
 	Status string `json:"status"`
 }
 
+// HAStatus is the high availability status of a guest
+type HAStatus struct {
+	Managed int     `json:"managed"`
+	Group   *string `json:"group,omitempty"`
+	State   *string `json:"state,omitempty"` // Requested HA state, like "started"
+}
+
 // IntOrString is an alias for some returns from the Proxmox API where we've identified that some versions return a string, and others return an integer
 // For example, LXC VMIDs were a string return in PVE 8.1.x, and are an integer in 8.2.x
 // Since it can be either depending on the version of Proxmox queried, we're going to return the looser type. String in this case.
@@ -0,0 +1,248 @@
+package proxmox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedVersion is matched by errors returned when an endpoint or parameter isn't available
+// on the Proxmox VE version of the server, see UnsupportedVersionError
+var ErrUnsupportedVersion = errors.New("unsupported Proxmox VE version")
+
+// Version is a parsed Proxmox VE version, e.g. 8.2.4
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion parses a Proxmox VE version like "8.2.4", "8.2", "7.4-3" or "pve-manager/8.2.4/faa83925c9641325"
+func ParseVersion(s string) (Version, error) {
+	v := strings.TrimSpace(s)
+	if strings.HasPrefix(v, "pve-manager/") {
+		v = strings.SplitN(strings.TrimPrefix(v, "pve-manager/"), "/", 2)[0]
+	}
+
+	// Older releases separate the patch version with a dash
+	parts := strings.Split(v, ".")
+	if base, patch, ok := strings.Cut(v, "-"); ok {
+		parts = append(strings.Split(base, "."), patch)
+		if len(parts) != 3 {
+			return Version{}, fmt.Errorf("invalid Proxmox VE version %q", s)
+		}
+	}
+	if len(parts) < 2 || len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid Proxmox VE version %q", s)
+	}
+
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || strings.HasPrefix(p, "+") {
+			return Version{}, fmt.Errorf("invalid Proxmox VE version %q", s)
+		}
+		nums[i] = n
+	}
+
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}, nil
+}
+
+// String returns the version in major.minor.patch format
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or higher than other
+func (v Version) Compare(other Version) int {
+	switch {
+	case v.Major != other.Major:
+		return compareInt(v.Major, other.Major)
+	case v.Minor != other.Minor:
+		return compareInt(v.Minor, other.Minor)
+	default:
+		return compareInt(v.Patch, other.Patch)
+	}
+}
+
+// AtLeast reports whether v is equal to or higher than other
+func (v Version) AtLeast(other Version) bool {
+	return v.Compare(other) >= 0
+}
+
+// compareInt returns -1, 0 or 1 if a is lower than, equal to or higher than b
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// UnsupportedVersionError is returned when a feature requires a newer Proxmox VE version than the server runs.
+// It matches ErrUnsupportedVersion with errors.Is.
+type UnsupportedVersionError struct {
+	// Feature is the endpoint or parameter that isn't available
+	Feature string
+
+	// Required is the lowest version the feature is available in
+	Required Version
+
+	// Actual is the version of the server
+	Actual Version
+}
+
+// Error implements the error interface for UnsupportedVersionError
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("%s requires Proxmox VE %s or later, the server runs %s", e.Feature, e.Required, e.Actual)
+}
+
+// Is reports whether target is ErrUnsupportedVersion
+func (e *UnsupportedVersionError) Is(target error) bool {
+	return target == ErrUnsupportedVersion
+}
+
+// serverVersion caches the version of the server a client is connected to
+type serverVersion struct {
+	mu      sync.Mutex
+	version *Version
+
+	// detecting is the request for the version in flight, nil if there is none
+	detecting *versionCall
+}
+
+// versionCall is a request for the server version that concurrent callers of ServerVersion wait for
+type versionCall struct {
+	done    chan struct{}
+	version Version
+	err     error
+}
+
+// WithServerVersion sets the Proxmox VE version of the server, so it isn't requested from the /version endpoint.
+// Useful when the version is known, or the API token lacks the privileges to read it.
+func WithServerVersion(version string) ClientOptionFunc {
+	return func(c *Client) error {
+		v, err := ParseVersion(version)
+		if err != nil {
+			return err
+		}
+		c.serverVersion.version = &v
+		return nil
+	}
+}
+
+// VersionService is the service that encapsulates version API methods
+type VersionService struct {
+	client *Client
+}
+
+// GetVersionResponse contains the response for the /version endpoint
+type GetVersionResponse struct {
+	Data GetVersionData `json:"data"`
+}
+
+// GetVersionData contains the API version data from a GetVersion request
+type GetVersionData struct {
+	Release string `json:"release"`
+	RepoID  string `json:"repoid"`
+	Version string `json:"version"`
+	Console string `json:"console,omitempty"`
+}
+
+// GetVersion makes a GET request to the /version endpoint
+// https://pve.proxmox.com/pve-docs/api-viewer/index.html#/version
+func (s *VersionService) GetVersion() (*GetVersionResponse, *http.Response, error) {
+	return s.GetVersionWithContext(context.Background())
+}
+
+// GetVersionWithContext is like GetVersion but uses the given context for the request
+func (s *VersionService) GetVersionWithContext(ctx context.Context) (*GetVersionResponse, *http.Response, error) {
+	u := "version"
+	req, err := s.client.NewRequestWithContext(withOperation(ctx, "VersionService.GetVersion"), http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := new(GetVersionResponse)
+	resp, err := s.client.Do(req, d)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return d, resp, nil
+}
+
+// ServerVersion returns the Proxmox VE version of the server. It's requested from the /version endpoint
+// on first use and cached for the lifetime of the client. Failed requests aren't cached.
+// Concurrent callers share a single request.
+func (c *Client) ServerVersion(ctx context.Context) (Version, error) {
+	for {
+		c.serverVersion.mu.Lock()
+		if c.serverVersion.version != nil {
+			v := *c.serverVersion.version
+			c.serverVersion.mu.Unlock()
+			return v, nil
+		}
+
+		call := c.serverVersion.detecting
+		if call == nil {
+			call = &versionCall{done: make(chan struct{})}
+			c.serverVersion.detecting = call
+			c.serverVersion.mu.Unlock()
+			c.detectVersion(ctx, call)
+			return call.version, call.err
+		}
+		c.serverVersion.mu.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return Version{}, ctx.Err()
+		}
+
+		// The request was made with the context of another caller, try again if only that context ended
+		if call.err == nil || !(errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded)) {
+			return call.version, call.err
+		}
+	}
+}
+
+// detectVersion requests the server version for a versionCall without holding the lock, and caches it on success
+func (c *Client) detectVersion(ctx context.Context, call *versionCall) {
+	defer close(call.done)
+
+	d, _, err := c.Version.GetVersionWithContext(ctx)
+	if err == nil {
+		call.version, err = ParseVersion(d.Data.Version)
+	} else {
+		err = fmt.Errorf("error detecting Proxmox VE version: %w", err)
+	}
+	call.err = err
+
+	c.serverVersion.mu.Lock()
+	defer c.serverVersion.mu.Unlock()
+	c.serverVersion.detecting = nil
+	if err == nil {
+		c.serverVersion.version = &call.version
+	}
+}
+
+// RequireVersion returns an UnsupportedVersionError if the server runs a Proxmox VE version older than required.
+// The feature names the endpoint or parameter in the error.
+func (c *Client) RequireVersion(ctx context.Context, required Version, feature string) error {
+	v, err := c.ServerVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if !v.AtLeast(required) {
+		return &UnsupportedVersionError{Feature: feature, Required: required, Actual: v}
+	}
+	return nil
+}
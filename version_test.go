@@ -0,0 +1,169 @@
+package proxmox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := map[string]Version{
+		"8.2.4":                               {Major: 8, Minor: 2, Patch: 4},
+		"8.2":                                 {Major: 8, Minor: 2},
+		"7.4-3":                               {Major: 7, Minor: 4, Patch: 3},
+		"pve-manager/8.1.10/4b06efb5db453f29": {Major: 8, Minor: 1, Patch: 10},
+	}
+	for s, want := range tests {
+		v, err := ParseVersion(s)
+		require.NoError(t, err, s)
+		require.Equal(t, want, v, s)
+	}
+
+	for _, s := range []string{"", "8", "8.x", "8.2.4.1", "-1.2", "8..2", "8.2-", "8-2", "8.+2"} {
+		_, err := ParseVersion(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestVersion_Compare(t *testing.T) {
+	v := Version{Major: 8, Minor: 2, Patch: 4}
+	require.Equal(t, "8.2.4", v.String())
+	require.Equal(t, 0, v.Compare(Version{Major: 8, Minor: 2, Patch: 4}))
+	require.Equal(t, 1, v.Compare(Version{Major: 8, Minor: 1, Patch: 10}))
+	require.Equal(t, -1, v.Compare(Version{Major: 9}))
+	require.True(t, v.AtLeast(Version{Major: 8, Minor: 2}))
+	require.False(t, v.AtLeast(Version{Major: 8, Minor: 2, Patch: 5}))
+}
+
+func TestGetVersion(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	mux.HandleFunc("/api2/json/version", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err := fmt.Fprint(w, fixture("version/get_version.json"))
+		if err != nil {
+			return
+		}
+	})
+
+	want := GetVersionResponse{
+		Data: GetVersionData{Release: "8.2", RepoID: "faa83925c9641325", Version: "8.2.4", Console: "xtermjs"},
+	}
+
+	r, resp, err := client.Version.GetVersion()
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.Equal(t, want, *r)
+}
+
+func TestClient_ServerVersion(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	var calls int32
+	mux.HandleFunc("/api2/json/version", func(w http.ResponseWriter, r *http.Request) {
+		// The first request fails, which mustn't be cached
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = fmt.Fprint(w, fixture("version/get_version.json"))
+	})
+
+	_, err := client.ServerVersion(context.Background())
+	require.Error(t, err)
+
+	var wg sync.WaitGroup
+	versions := make([]Version, 5)
+	errs := make([]error, 5)
+	for i := range versions {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			versions[i], errs[i] = client.ServerVersion(context.Background())
+		}(i)
+	}
+	wg.Wait()
+	for i := range versions {
+		require.NoError(t, errs[i])
+		require.Equal(t, Version{Major: 8, Minor: 2, Patch: 4}, versions[i])
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	require.NoError(t, client.RequireVersion(context.Background(), Version{Major: 8, Minor: 1}, "overrule-shutdown"))
+
+	err = client.RequireVersion(context.Background(), Version{Major: 8, Minor: 3}, "the feature")
+	require.True(t, errors.Is(err, ErrUnsupportedVersion))
+	require.EqualError(t, err, "the feature requires Proxmox VE 8.3.0 or later, the server runs 8.2.4")
+
+	var versionErr *UnsupportedVersionError
+	require.True(t, errors.As(err, &versionErr))
+	require.Equal(t, Version{Major: 8, Minor: 3}, versionErr.Required)
+}
+
+func TestWithServerVersion(t *testing.T) {
+	c, err := NewClient("test-token-id", "test-token", WithServerVersion("7.4-3"))
+	require.NoError(t, err)
+
+	// No request is made, the base URL can't be reached
+	v, err := c.ServerVersion(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, Version{Major: 7, Minor: 4, Patch: 3}, v)
+
+	_, err = NewClient("test-token-id", "test-token", WithServerVersion("latest"))
+	require.Error(t, err)
+}
+
+func TestServerVersionDetecting(t *testing.T) {
+	mux, server, client := setup(t)
+	defer teardown(server)
+
+	var calls int32
+	entered := make(chan struct{})
+	mux.HandleFunc("/api2/json/version", func(w http.ResponseWriter, r *http.Request) {
+		// The first request hangs until its context is canceled
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(entered)
+			<-r.Context().Done()
+			return
+		}
+		_, _ = fmt.Fprint(w, fixture("version/get_version.json"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := client.ServerVersion(ctx)
+		first <- err
+	}()
+	<-entered
+
+	// Callers don't wait for the request in flight longer than their context allows
+	timeoutCtx, timeoutCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer timeoutCancel()
+	_, err := client.ServerVersion(timeoutCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Callers waiting for a request canceled by another caller's context request the version again
+	second := make(chan error, 1)
+	go func() {
+		v, err := client.ServerVersion(context.Background())
+		if err == nil && v != (Version{Major: 8, Minor: 2, Patch: 4}) {
+			err = fmt.Errorf("unexpected version %s", v)
+		}
+		second <- err
+	}()
+	cancel()
+	require.ErrorIs(t, <-first, context.Canceled)
+	require.NoError(t, <-second)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}